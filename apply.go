@@ -0,0 +1,260 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type ApplyCommand struct {
+	File  string   `flag:"" name:"file" short:"f" help:"Project file (.toml or .yaml)." type:"path" default:"rove.toml"`
+	Tasks []string `flag:"" name:"task" help:"Name of task from project file to run after services are deployed."`
+
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force       bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
+	Verbose     bool   `flag:"" name:"verbose"`
+
+	project *Project
+}
+
+func (cmd *ApplyCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	networksExisting := make([]string, 0)
+	secretsExisting := make([]string, 0)
+	volumesExisting := make([]string, 0)
+	err := conn.
+		Run("docker network ls --format json --filter label=rove", func(res string) error {
+			for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+				if line != "" {
+					var dockerNetworkLs DockerNetworkLsJson
+					if err := json.Unmarshal([]byte(line), &dockerNetworkLs); err != nil {
+						fmt.Fprintln(stdout, "🚫 Could not parse docker network ls JSON:\n", line)
+						return err
+					}
+					networksExisting = append(networksExisting, dockerNetworkLs.Name)
+				}
+			}
+			return nil
+		}).
+		Run("docker volume ls --format json --filter label=rove", func(res string) error {
+			for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+				if line != "" {
+					var dockerVolumeLs DockerVolumeLsJson
+					if err := json.Unmarshal([]byte(line), &dockerVolumeLs); err != nil {
+						fmt.Fprintln(stdout, "🚫 Could not parse docker volume ls JSON:\n", line)
+						return err
+					}
+					volumesExisting = append(volumesExisting, dockerVolumeLs.Name)
+				}
+			}
+			return nil
+		}).
+		Run("docker secret ls --format json --filter label=rove", func(res string) error {
+			for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+				if line != "" {
+					var dockerSecretLs DockerSecretLsJson
+					if err := json.Unmarshal([]byte(line), &dockerSecretLs); err != nil {
+						fmt.Fprintln(stdout, "🚫 Could not parse docker secret ls JSON:\n", line)
+						return err
+					}
+					secretsExisting = append(secretsExisting, dockerSecretLs.Name)
+				}
+			}
+			return nil
+		}).
+		Error()
+	if err != nil {
+		fmt.Fprintln(stdout, "🚫 Could not create deployment plan")
+		return err
+	}
+
+	// Secret contents never live in the project file, so they must be created beforehand.
+	for _, secret := range cmd.project.Secrets {
+		if !slices.Contains(secretsExisting, secret) {
+			return fmt.Errorf("🚫 Secret '%s' does not exist. Create it with `rove secret create %s <file>` before applying", secret, secret)
+		}
+	}
+
+	networks := make([]*NetworkAddCommand, 0)
+	for _, name := range slices.Sorted(maps.Keys(cmd.project.Networks)) {
+		if !slices.Contains(networksExisting, name) {
+			networks = append(networks, &NetworkAddCommand{Name: name})
+		}
+	}
+
+	volumes := make([]*VolumeAddCommand, 0)
+	for _, name := range slices.Sorted(maps.Keys(cmd.project.Volumes)) {
+		if !slices.Contains(volumesExisting, name) {
+			volume := cmd.project.Volumes[name]
+			volumes = append(volumes, &VolumeAddCommand{
+				Driver: volume.Driver,
+				Name:   name,
+				Opt:    volume.Opt,
+			})
+		}
+	}
+
+	plans := make([]*ServicePlan, 0)
+	for _, service := range cmd.project.ServiceRunCommands() {
+		service.Verbose = cmd.Verbose
+		plan, err := service.Plan(conn)
+		if err != nil {
+			fmt.Fprintf(stdout, "🚫 Could not create deployment plan for '%s'\n", service.Name)
+			return err
+		}
+		plans = append(plans, plan)
+	}
+
+	tasks := make([]*ServicePlan, 0)
+	for _, name := range cmd.Tasks {
+		task, err := cmd.project.TaskRunCommand(name)
+		if err != nil {
+			return err
+		}
+		task.Verbose = cmd.Verbose
+		tasks = append(tasks, task.Plan())
+	}
+
+	changed := len(networks) > 0 || len(volumes) > 0 || len(tasks) > 0 || slices.ContainsFunc(plans, func(plan *ServicePlan) bool {
+		return plan.Status() != DiffSame
+	})
+	if !changed {
+		fmt.Fprint(stdout, "\nNo changes.\n\n")
+		return nil
+	}
+
+	fmt.Fprint(stdout, "\nRove will make the following changes:\n\n")
+	for _, network := range networks {
+		fmt.Fprintf(stdout, " + network %s\n", network.Name)
+	}
+	for _, volume := range volumes {
+		fmt.Fprintf(stdout, " + volume %s\n", volume.Name)
+	}
+	for _, plan := range plans {
+		fmt.Fprintln(stdout, plan)
+	}
+	for i, plan := range tasks {
+		diffText, _ := plan.New.Diff(plan.Old)
+		fmt.Fprintf(stdout, " + task %s:\n", cmd.Tasks[i])
+		fmt.Fprintln(stdout, diffText)
+	}
+	if err := confirmDeploymentTo(stdout, cmd.Force, stdin); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "\nDeploying...")
+
+	for _, network := range networks {
+		err := conn.
+			Run(network.Command(), func(_ string) error {
+				fmt.Fprintf(stdout, "\nCreated '%s' network.\n", network.Name)
+				return nil
+			}).
+			OnError(func(err error) error {
+				if err != nil {
+					fmt.Fprintln(stdout, "🚫 Could not add network")
+				}
+				return err
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, volume := range volumes {
+		err := conn.
+			Run(volume.Command().String(), func(_ string) error {
+				fmt.Fprintf(stdout, "\nCreated '%s' volume.\n", volume.Name)
+				return nil
+			}).
+			OnError(func(err error) error {
+				if err != nil {
+					fmt.Fprintln(stdout, "🚫 Could not add volume")
+				}
+				return err
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, plan := range plans {
+		// Unchanged services are not redeployed, so that applying a project again does nothing.
+		if plan.Status() == DiffSame {
+			continue
+		}
+		err := conn.
+			Stream(plan.CommandPull.String(), StreamHandler{
+				Stdout: func(line string) error {
+					if cmd.Verbose {
+						fmt.Fprintf(stdout, "\n[verbose] %s: %s", plan.CommandPull.String(), line)
+					}
+					return nil
+				},
+			}).
+			Run(plan.Command.String(), func(_ string) error {
+				fmt.Fprintf(stdout, "\nRove deployed '%s'.\n", plan.Name)
+				return nil
+			}).
+			OnError(func(err error) error {
+				if err != nil {
+					fmt.Fprintf(stdout, "🚫 Could not deploy service '%s'\n", plan.Name)
+				}
+				return err
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, plan := range tasks {
+		err := conn.
+			Stream(plan.CommandPull.String(), StreamHandler{
+				Stdout: func(line string) error {
+					if cmd.Verbose {
+						fmt.Fprintf(stdout, "\n[verbose] %s: %s", plan.CommandPull.String(), line)
+					}
+					return nil
+				},
+			}).
+			Run(plan.Command.String(), func(res string) error {
+				fmt.Fprintf(stdout, "\nRove deployed task '%s': %s", cmd.Tasks[i], res)
+				return nil
+			}).
+			OnError(func(err error) error {
+				if err != nil {
+					fmt.Fprintf(stdout, "🚫 Could not deploy task '%s'\n", cmd.Tasks[i])
+				}
+				return err
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprint(stdout, "\nRove applied project.\n\n")
+	return nil
+}
+
+func (cmd *ApplyCommand) Run(ctx context.Context) error {
+	project, err := ParseProject(cmd.File)
+	if err != nil {
+		return err
+	}
+	cmd.project = project
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, false, cmd.Do)
+	})
+}
@@ -0,0 +1,93 @@
+package rove
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestApplyCommand(t *testing.T) {
+	if err := testDatabase(func() error {
+		mock := &SshConnectionMock{}
+		expectedCmd := []string{
+			"docker network ls --format json --filter label=rove",
+			"docker volume ls --format json --filter label=rove",
+			"docker secret ls --format json --filter label=rove",
+			"docker service ls --format json --filter label=rove=service --filter name=files",
+			"docker network create --attachable --driver overlay --label rove --scope swarm backend",
+			"docker volume create --driver local --label rove --name data",
+			"docker image pull --quiet python:3.12",
+			"docker service create --replicas 1 --update-delay 0s --update-failure-action pause --update-order stop-first --update-parallelism 1 --user '' --workdir '' --label rove=service --name files --network backend --publish 80:80 python:3.12",
+		}
+		expected := fmt.Sprint(
+			"\nRove will make the following changes:\n\n",
+			" + network backend\n",
+			" + volume data\n",
+			" + service files:\n",
+			" +   image    = \"python:3.12\"\n",
+			" +   network  = [\"backend\"]\n",
+			" +   publish  = [\"80:80\"]\n",
+			" +   replicas = \"1\"\n\n",
+			"Confirmations skipped.\n\n",
+			"Deploying...\n\n",
+			"Created 'backend' network.\n\n",
+			"Created 'data' volume.\n\n",
+			"Rove deployed 'files'.\n\n",
+			"Rove applied project.\n\n")
+
+		capture(t).
+			Run(func() error {
+				cmd := &ApplyCommand{
+					Force:   true,
+					Machine: "default",
+					project: &Project{
+						Networks: map[string]ProjectNetwork{"backend": {}},
+						Services: map[string]ProjectService{
+							"files": {
+								Image:    "python:3.12",
+								Networks: []string{"backend"},
+								Publish:  []string{"80:80"},
+							},
+						},
+						Volumes: map[string]ProjectVolume{"data": {Driver: "local"}},
+					},
+				}
+				return cmd.Do(mock, nil)
+			}).
+			ExpectStdout(expected)
+
+		if !slices.Equal(mock.CommandsRun, expectedCmd) {
+			t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyCommandNoChanges(t *testing.T) {
+	if err := testDatabase(func() error {
+		mock := &SshConnectionMock{}
+		capture(t).
+			Run(func() error {
+				cmd := &ApplyCommand{
+					Machine: "default",
+					project: &Project{},
+				}
+				return cmd.Do(mock, nil)
+			}).
+			ExpectStdout("\nNo changes.\n\n")
+
+		expectedCmd := []string{
+			"docker network ls --format json --filter label=rove",
+			"docker volume ls --format json --filter label=rove",
+			"docker secret ls --format json --filter label=rove",
+		}
+		if !slices.Equal(mock.CommandsRun, expectedCmd) {
+			t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
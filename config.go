@@ -0,0 +1,339 @@
+package rove
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/evantbyrne/trance"
+	"gopkg.in/yaml.v3"
+)
+
+// configVersion is the format of exported configuration. Imports of newer versions are refused.
+const configVersion = 1
+
+type ConfigJson struct {
+	Machines    []ConfigMachineJson `json:"machines" yaml:"machines"`
+	Preferences map[string]string   `json:"preferences,omitempty" yaml:"preferences,omitempty"`
+	Version     int                 `json:"version" yaml:"version"`
+}
+
+type ConfigMachineJson struct {
+	Address            string   `json:"address,omitempty" yaml:"address,omitempty"`
+	Cluster            string   `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	CommandTimeout     int64    `json:"command_timeout,omitempty" yaml:"command_timeout,omitempty"`
+	ConnectTimeout     int64    `json:"connect_timeout,omitempty" yaml:"connect_timeout,omitempty"`
+	DockerVersion      string   `json:"docker_version,omitempty" yaml:"docker_version,omitempty"`
+	Groups             []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	HostKeyFingerprint string   `json:"host_key_fingerprint,omitempty" yaml:"host_key_fingerprint,omitempty"`
+	Keepalive          int64    `json:"keepalive,omitempty" yaml:"keepalive,omitempty"`
+	Key                string   `json:"key,omitempty" yaml:"key,omitempty"`
+	Name               string   `json:"name" yaml:"name"`
+	Os                 string   `json:"os,omitempty" yaml:"os,omitempty"`
+	Port               int64    `json:"port,omitempty" yaml:"port,omitempty"`
+	ProxyJump          string   `json:"proxy_jump,omitempty" yaml:"proxy_jump,omitempty"`
+	SshConfigHost      string   `json:"ssh_config_host,omitempty" yaml:"ssh_config_host,omitempty"`
+	User               string   `json:"user,omitempty" yaml:"user,omitempty"`
+}
+
+// Machine converts an exported machine back into a row. Key paths may reference environment variables, such as $HOME/.ssh/id_ed25519 or ${DEPLOY_KEY}, which are expanded on import.
+func (config ConfigMachineJson) Machine() *Machine {
+	key := os.ExpandEnv(config.Key)
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(key, "~/") {
+		key = home + key[1:]
+	}
+	return &Machine{
+		Address:            config.Address,
+		Cluster:            config.Cluster,
+		CommandTimeout:     config.CommandTimeout,
+		ConnectTimeout:     config.ConnectTimeout,
+		DockerVersion:      config.DockerVersion,
+		HostKeyFingerprint: config.HostKeyFingerprint,
+		Keepalive:          config.Keepalive,
+		KeyPath:            key,
+		Name:               config.Name,
+		Os:                 config.Os,
+		Port:               ternary(config.Port == 0, 22, config.Port),
+		ProxyJump:          config.ProxyJump,
+		SshConfigHost:      config.SshConfigHost,
+		User:               config.User,
+	}
+}
+
+type ConfigExportCommand struct {
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Format     string `flag:"" name:"format" help:"Output format. Either json or yaml." enum:"json,yaml" default:"json"`
+	NoKeys     bool   `flag:"" name:"no-keys" help:"Omit private key paths, so that imported machines authenticate with the SSH agent."`
+	Output     string `flag:"" name:"output" short:"o" help:"Write to file instead of STDOUT." type:"path"`
+}
+
+func (cmd *ConfigExportCommand) Do() error {
+	machines, err := trance.Query[Machine]().Sort("name").All().Collect()
+	if err != nil {
+		return err
+	}
+	memberships, err := trance.Query[MachineGroup]().Sort("name").All().Collect()
+	if err != nil {
+		return err
+	}
+	preferences, err := trance.Query[Preference]().All().Collect()
+	if err != nil {
+		return err
+	}
+
+	output := ConfigJson{
+		Machines: make([]ConfigMachineJson, 0, len(machines)),
+		Version:  configVersion,
+	}
+	for _, machine := range machines {
+		config := ConfigMachineJson{
+			Address:            machine.Address,
+			Cluster:            machine.Cluster,
+			CommandTimeout:     machine.CommandTimeout,
+			ConnectTimeout:     machine.ConnectTimeout,
+			DockerVersion:      machine.DockerVersion,
+			HostKeyFingerprint: machine.HostKeyFingerprint,
+			Keepalive:          machine.Keepalive,
+			Name:               machine.Name,
+			Os:                 machine.Os,
+			Port:               machine.Port,
+			ProxyJump:          machine.ProxyJump,
+			SshConfigHost:      machine.SshConfigHost,
+			User:               machine.User,
+		}
+		if !cmd.NoKeys {
+			config.Key = machine.KeyPath
+		}
+		for _, membership := range memberships {
+			if membership.MachineId == machine.Id {
+				config.Groups = append(config.Groups, membership.Name)
+			}
+		}
+		output.Machines = append(output.Machines, config)
+	}
+	if len(preferences) > 0 {
+		output.Preferences = make(map[string]string)
+		for _, preference := range preferences {
+			output.Preferences[preference.Name] = preference.Value
+		}
+	}
+
+	var out []byte
+	if cmd.Format == "yaml" {
+		out, err = yaml.Marshal(output)
+	} else {
+		out, err = json.MarshalIndent(output, "", "    ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		fmt.Println("🚫 Could not format config:\n", output)
+		return err
+	}
+	if cmd.Output == "" {
+		fmt.Print(string(out))
+		return nil
+	}
+	if err := os.WriteFile(cmd.Output, out, 0644); err != nil {
+		return fmt.Errorf("unable to write config export: %v", err)
+	}
+	fmt.Printf("✅ Exported %d machines to '%s'\n", len(output.Machines), cmd.Output)
+	return nil
+}
+
+func (cmd *ConfigExportCommand) Run() error {
+	return Database(cmd.ConfigFile, cmd.Do)
+}
+
+type ConfigImportCommand struct {
+	File string `arg:"" name:"file" help:"JSON or YAML file from rove config export. Use - to read from STDIN."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	DryRun     bool   `flag:"" name:"dry-run" help:"Report changes and conflicts without importing."`
+	Overwrite  bool   `flag:"" name:"overwrite" help:"Replace conflicting machines and preferences with imported ones."`
+
+	stdin io.Reader
+}
+
+func (cmd *ConfigImportCommand) Do() error {
+	var data []byte
+	var err error
+	if cmd.File == "-" {
+		stdin := cmd.stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(cmd.File)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read config import: %v", err)
+	}
+	// JSON is valid YAML, so both formats are parsed the same way.
+	var input ConfigJson
+	if err := yaml.Unmarshal(data, &input); err != nil {
+		return fmt.Errorf("unable to parse config import '%s': %v", cmd.File, err)
+	}
+	if input.Version > configVersion {
+		return fmt.Errorf("🚫 Config import '%s' has version %d, but this version of Rove only supports version %d. Upgrade Rove to import it", cmd.File, input.Version, configVersion)
+	}
+	for _, config := range input.Machines {
+		if config.Name == "" {
+			return fmt.Errorf("🚫 Config import '%s' contains a machine without a name", cmd.File)
+		}
+	}
+
+	machines, err := trance.Query[Machine]().All().Collect()
+	if err != nil {
+		return err
+	}
+	memberships, err := trance.Query[MachineGroup]().All().Collect()
+	if err != nil {
+		return err
+	}
+	preferences, err := trance.Query[Preference]().All().Collect()
+	if err != nil {
+		return err
+	}
+
+	// Changes are made in one transaction, so that an import that fails part way leaves the config unchanged.
+	tx, err := trance.Database().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	conflicts := 0
+	for _, config := range input.Machines {
+		imported := config.Machine()
+		index := slices.IndexFunc(machines, func(machine *Machine) bool {
+			return machine.Name == imported.Name
+		})
+		if index < 0 {
+			if !cmd.DryRun {
+				if err := trance.Query[Machine]().Transaction(tx).Insert(imported).Error; err != nil {
+					fmt.Printf("🚫 Could not add machine '%s'\n", imported.Name)
+					return err
+				}
+				inserted, err := trance.Query[Machine]().Transaction(tx).Filter("name", "=", imported.Name).CollectFirst()
+				if err != nil {
+					return err
+				}
+				imported.Id = inserted.Id
+			}
+			fmt.Printf("✅ Added machine '%s'\n", imported.Name)
+		} else {
+			imported.Id = machines[index].Id
+			if diff := configMachineDiff(machines[index], imported); len(diff) == 0 {
+				fmt.Printf("✅ Machine '%s' unchanged\n", imported.Name)
+			} else if cmd.Overwrite {
+				if !cmd.DryRun {
+					if err := trance.Query[Machine]().Transaction(tx).Filter("id", "=", imported.Id).UpdateMap(configMachineColumns(imported)).Error; err != nil {
+						fmt.Printf("🚫 Could not update machine '%s'\n", imported.Name)
+						return err
+					}
+				}
+				fmt.Printf("✅ Replaced machine '%s': %s\n", imported.Name, strings.Join(diff, ", "))
+			} else {
+				conflicts++
+				fmt.Printf("🚫 Conflict on machine '%s': %s\n", imported.Name, strings.Join(diff, ", "))
+				continue
+			}
+		}
+
+		for _, group := range config.Groups {
+			member := slices.ContainsFunc(memberships, func(membership *MachineGroup) bool {
+				return membership.MachineId == imported.Id && membership.Name == group
+			})
+			if member {
+				continue
+			}
+			if !cmd.DryRun {
+				if err := trance.Query[MachineGroup]().Transaction(tx).Insert(&MachineGroup{MachineId: imported.Id, Name: group}).Error; err != nil {
+					fmt.Printf("🚫 Could not add machine '%s' to group '%s'\n", imported.Name, group)
+					return err
+				}
+			}
+			fmt.Printf("✅ Added machine '%s' to group '%s'\n", imported.Name, group)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(input.Preferences)) {
+		value := input.Preferences[name]
+		index := slices.IndexFunc(preferences, func(preference *Preference) bool {
+			return preference.Name == name
+		})
+		switch {
+		case index >= 0 && preferences[index].Value == value:
+			fmt.Printf("✅ Preference '%s' unchanged\n", name)
+			continue
+		case index >= 0 && !cmd.Overwrite:
+			conflicts++
+			fmt.Printf("🚫 Conflict on preference '%s': '%s' => '%s'\n", name, preferences[index].Value, value)
+			continue
+		}
+		if !cmd.DryRun {
+			query := trance.Query[Preference]().Transaction(tx)
+			if index >= 0 {
+				err = query.Filter("name", "=", name).UpdateMap(map[string]any{"value": value}).Error
+			} else {
+				err = query.InsertMap(map[string]any{"name": name, "value": value}).Error
+			}
+			if err != nil {
+				fmt.Printf("🚫 Could not set preference '%s'\n", name)
+				return err
+			}
+		}
+		fmt.Printf("✅ Set preference '%s' to '%s'\n", name, value)
+	}
+
+	if cmd.DryRun {
+		fmt.Println("\nDry run. No changes were saved.")
+	} else if err := tx.Commit(); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("🚫 %d conflicting entries were not imported. Pass --overwrite to replace them", conflicts)
+	}
+	return nil
+}
+
+func (cmd *ConfigImportCommand) Run() error {
+	return DatabaseCreate(cmd.ConfigFile, cmd.Do)
+}
+
+// configMachineColumns returns every imported column, for replacing a machine.
+func configMachineColumns(machine *Machine) map[string]any {
+	return map[string]any{
+		"address":              machine.Address,
+		"cluster":              machine.Cluster,
+		"command_timeout":      machine.CommandTimeout,
+		"connect_timeout":      machine.ConnectTimeout,
+		"docker_version":       machine.DockerVersion,
+		"host_key_fingerprint": machine.HostKeyFingerprint,
+		"keepalive":            machine.Keepalive,
+		"key_path":             machine.KeyPath,
+		"os":                   machine.Os,
+		"port":                 machine.Port,
+		"proxy_jump":           machine.ProxyJump,
+		"ssh_config_host":      machine.SshConfigHost,
+		"user":                 machine.User,
+	}
+}
+
+// configMachineDiff describes the columns in which an imported machine differs from the local one.
+func configMachineDiff(local *Machine, imported *Machine) []string {
+	localColumns := configMachineColumns(local)
+	importedColumns := configMachineColumns(imported)
+	diff := make([]string, 0)
+	for _, column := range slices.Sorted(maps.Keys(localColumns)) {
+		if localColumns[column] != importedColumns[column] {
+			diff = append(diff, fmt.Sprintf("%s '%v' => '%v'", column, localColumns[column], importedColumns[column]))
+		}
+	}
+	return diff
+}
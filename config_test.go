@@ -0,0 +1,114 @@
+package rove
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evantbyrne/trance"
+)
+
+func TestConfigExportImport(t *testing.T) {
+	if err := testDatabase(func() error {
+		if err := trance.Query[Machine]().Insert(&Machine{Address: "192.0.2.1", HostKeyFingerprint: "SHA256:abc", KeyPath: "/keys/prod", Name: "prod", Port: 22, User: "root"}).Error; err != nil {
+			return err
+		}
+		defer trance.Query[Machine]().Delete()
+		defer trance.Query[MachineGroup]().Delete()
+		defer trance.Query[Preference]().Delete()
+		capture(t).Run(func() error {
+			return (&MachineGroupAddCommand{Group: "web", Machines: []string{"prod"}}).Do()
+		})
+		if err := SetPreference(DefaultMachine, "prod").Error; err != nil {
+			return err
+		}
+
+		capture(t).Run(func() error {
+			return (&ConfigExportCommand{Format: "yaml", NoKeys: true}).Do()
+		}).ExpectStdout(`machines:
+    - address: 192.0.2.1
+      groups:
+        - web
+      host_key_fingerprint: SHA256:abc
+      name: prod
+      port: 22
+      user: root
+preferences:
+    default_machine_name: prod
+version: 1
+`)
+
+		file := filepath.Join(t.TempDir(), "rove.json")
+		capture(t).Run(func() error {
+			return (&ConfigExportCommand{Format: "json", Output: file}).Do()
+		}).ExpectStdout("✅ Exported 1 machines to '" + file + "'\n")
+
+		capture(t).Run(func() error {
+			return (&ConfigImportCommand{File: file}).Do()
+		}).ExpectStdout("✅ Machine 'prod' unchanged\n✅ Preference 'default_machine_name' unchanged\n")
+
+		if err := trance.Query[Machine]().Filter("name", "=", "prod").UpdateMap(map[string]any{"address": "192.0.2.2"}).Error; err != nil {
+			return err
+		}
+		capture(t).Run(func() error {
+			err := (&ConfigImportCommand{File: file}).Do()
+			if err == nil || !strings.Contains(err.Error(), "1 conflicting entries") {
+				t.Errorf("expected conflict, got %v", err)
+			}
+			return nil
+		}).ExpectStdout("🚫 Conflict on machine 'prod': address '192.0.2.2' => '192.0.2.1'\n✅ Preference 'default_machine_name' unchanged\n")
+
+		capture(t).Run(func() error {
+			return (&ConfigImportCommand{File: file, Overwrite: true}).Do()
+		}).ExpectStdout("✅ Replaced machine 'prod': address '192.0.2.2' => '192.0.2.1'\n✅ Preference 'default_machine_name' unchanged\n")
+
+		if err := trance.Query[MachineGroup]().Delete().Error; err != nil {
+			return err
+		}
+		if err := trance.Query[Machine]().Delete().Error; err != nil {
+			return err
+		}
+		capture(t).Run(func() error {
+			return (&ConfigImportCommand{File: "-", DryRun: true, stdin: strings.NewReader("version: 1\nmachines:\n  - name: ci\n    address: 192.0.2.5\n    user: deploy\n")}).Do()
+		}).ExpectStdout("✅ Added machine 'ci'\n\nDry run. No changes were saved.\n")
+		if machines, err := trance.Query[Machine]().All().Collect(); err != nil || len(machines) != 0 {
+			t.Errorf("dry run saved machines: %v %v", machines, err)
+		}
+
+		capture(t).Run(func() error {
+			return (&ConfigImportCommand{File: file}).Do()
+		}).ExpectStdout("✅ Added machine 'prod'\n✅ Added machine 'prod' to group 'web'\n✅ Preference 'default_machine_name' unchanged\n")
+		machine, err := trance.Query[Machine]().Filter("name", "=", "prod").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if machine.Address != "192.0.2.1" || machine.KeyPath != "/keys/prod" || machine.HostKeyFingerprint != "SHA256:abc" {
+			t.Errorf("'%#v' was not imported.", machine)
+		}
+		if machines, err := machinesSelect("web"); err != nil || len(machines) != 1 {
+			t.Errorf("group was not imported: %v %v", machines, err)
+		}
+
+		// The second machine fails to insert because of its duplicate name, which rolls back the first.
+		capture(t).Run(func() error {
+			if err := (&ConfigImportCommand{File: "-", stdin: strings.NewReader("version: 1\nmachines:\n  - name: ci\n    address: 192.0.2.5\n  - name: ci\n    address: 192.0.2.6\n")}).Do(); err == nil {
+				t.Error("expected duplicate machine to fail.")
+			}
+			return nil
+		})
+		if _, err := trance.Query[Machine]().Filter("name", "=", "ci").CollectFirst(); !errors.Is(err, trance.ErrorNotFound{}) {
+			t.Errorf("failed import was not rolled back: %v", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigMachineKey(t *testing.T) {
+	t.Setenv("DEPLOY_KEY", "/run/keys/deploy")
+	if machine := (ConfigMachineJson{Key: "${DEPLOY_KEY}", Name: "ci"}).Machine(); machine.KeyPath != "/run/keys/deploy" || machine.Port != 22 {
+		t.Errorf("'%#v' did not match expected.", machine)
+	}
+}
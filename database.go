@@ -3,6 +3,8 @@ package rove
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/evantbyrne/rove/migrations"
 	"github.com/evantbyrne/trance"
@@ -11,9 +13,80 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// databaseMemory is the SQLite source used when there is no config file to read.
+const databaseMemory = "file::memory:?cache=shared"
+
+// databasePath is the config file opened by the running command, or empty when it runs without one.
+var databasePath string
+
+// Database opens the config file and runs callback. An empty file is found with configFind. Commands that only read configuration never create a config file, and run against an empty in-memory database when none is found.
 func Database(file string, callback func() error) error {
+	path, found := configFind(file)
+	if !found {
+		if file != "" && os.Getenv(MachineEnvAddress) == "" {
+			return fmt.Errorf("🚫 Config file '%s' does not exist", file)
+		}
+		// CI jobs that describe their machine with environment variables should not leave a config file behind.
+		databasePath = ""
+		return databaseOpen(databaseMemory, callback)
+	}
+	databasePath = path
+	return databaseOpen(fmt.Sprint("file:", path), callback)
+}
+
+// DatabaseCreate is Database for commands that save configuration. It creates the config file when none is found, at $XDG_CONFIG_HOME/rove/config unless a file is given.
+func DatabaseCreate(file string, callback func() error) error {
+	path, found := configFind(file)
+	if !found {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("unable to create config directory: %v", err)
+		}
+		fmt.Printf("Creating config file '%s'.\n", path)
+	}
+	databasePath = path
+	return databaseOpen(fmt.Sprint("file:", path), callback)
+}
+
+// configFind returns the config file to use and whether it exists. Without an explicit file, it looks for .rove in the working directory and its parents, then falls back to $XDG_CONFIG_HOME/rove/config.
+func configFind(file string) (string, bool) {
+	if file != "" {
+		return file, configExists(file)
+	}
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			if path := filepath.Join(dir, ".rove"); configExists(path) {
+				return path, true
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	path := configUserPath()
+	return path, configExists(path)
+}
+
+func configExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// configUserPath is the per-user config file. $XDG_CONFIG_HOME defaults to ~/.config on every OS.
+func configUserPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config")
+		}
+	}
+	return filepath.Join(dir, "rove", "config")
+}
+
+func databaseOpen(source string, callback func() error) error {
 	trance.SetDialect(sqlitedialect.SqliteDialect{})
-	db, err := sql.Open("sqlite", fmt.Sprint("file:", file))
+	db, err := sql.Open("sqlite", source)
 	if err != nil {
 		return err
 	}
@@ -23,6 +96,15 @@ func Database(file string, callback func() error) error {
 
 	_, err = trance.MigrateUp([]trance.Migration{
 		migrations.Migration0001Init{},
+		migrations.Migration0002HostKey{},
+		migrations.Migration0003ProxyJump{},
+		migrations.Migration0004SshConfigHost{},
+		migrations.Migration0005SshTimeouts{},
+		migrations.Migration0006MachineGroups{},
+		migrations.Migration0007MachineCluster{},
+		migrations.Migration0008MachineOs{},
+		migrations.Migration0009DockerVersion{},
+		migrations.Migration0010ClusterId{},
 	})
 	if err != nil {
 		return err
@@ -43,6 +125,15 @@ func testDatabase(callback func() error) error {
 
 	_, err = trance.MigrateUp([]trance.Migration{
 		migrations.Migration0001Init{},
+		migrations.Migration0002HostKey{},
+		migrations.Migration0003ProxyJump{},
+		migrations.Migration0004SshConfigHost{},
+		migrations.Migration0005SshTimeouts{},
+		migrations.Migration0006MachineGroups{},
+		migrations.Migration0007MachineCluster{},
+		migrations.Migration0008MachineOs{},
+		migrations.Migration0009DockerVersion{},
+		migrations.Migration0010ClusterId{},
 	})
 	if err != nil {
 		return err
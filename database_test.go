@@ -0,0 +1,70 @@
+package rove
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigFind(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	nested := filepath.Join(root, "project", "services", "web")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+
+	if path, found := configFind(""); found || path != filepath.Join(root, "xdg", "rove", "config") {
+		t.Errorf("'%s' %v did not match expected.", path, found)
+	}
+
+	user := filepath.Join(root, "xdg", "rove", "config")
+	if err := os.MkdirAll(filepath.Dir(user), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(user, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, found := configFind(""); !found || path != user {
+		t.Errorf("'%s' %v did not match expected.", path, found)
+	}
+
+	project := filepath.Join(root, "project", ".rove")
+	if err := os.WriteFile(project, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, found := configFind(""); !found || path != project {
+		t.Errorf("'%s' %v did not match expected.", path, found)
+	}
+
+	explicit := filepath.Join(root, "other.rove")
+	if path, found := configFind(explicit); found || path != explicit {
+		t.Errorf("'%s' %v did not match expected.", path, found)
+	}
+}
+
+func TestDatabaseCreate(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	t.Setenv(MachineEnvAddress, "")
+	t.Chdir(root)
+
+	if err := Database("", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := Database(filepath.Join(root, "missing.rove"), func() error { return nil }); err == nil {
+		t.Error("expected missing config file to fail.")
+	}
+	user := filepath.Join(root, "xdg", "rove", "config")
+	if _, err := os.Stat(user); !os.IsNotExist(err) {
+		t.Fatalf("read-only command created config file: %v", err)
+	}
+
+	capture(t).Run(func() error {
+		return DatabaseCreate("", func() error { return nil })
+	}).ExpectStdout("Creating config file '" + user + "'.\n")
+	if _, err := os.Stat(user); err != nil {
+		t.Errorf("expected config file: %v", err)
+	}
+}
@@ -19,6 +19,54 @@ func (line DiffLine) StringPadded(maxLeft int) string {
 	return fmt.Sprintf(" %s   %s = %s", symbol, line.Left+pad, line.Right)
 }
 
+type DiffFieldJson struct {
+	Name   string          `json:"name"`
+	New    json.RawMessage `json:"new,omitempty"`
+	Old    json.RawMessage `json:"old,omitempty"`
+	Status string          `json:"status"`
+}
+
+func diffFieldsJson(lines []DiffLine) []DiffFieldJson {
+	fields := make([]DiffFieldJson, 0)
+	for _, line := range lines {
+		value := json.RawMessage(line.Right)
+		last := len(fields) - 1
+		if line.Status == DiffCreate && last >= 0 && fields[last].Name == line.Left && fields[last].Status == "delete" {
+			// Changed values are represented as a deletion followed by a creation.
+			fields[last].New = value
+			fields[last].Status = "update"
+			continue
+		}
+		field := DiffFieldJson{
+			Name:   line.Left,
+			Status: diffStatusJson(line.Status),
+		}
+		switch line.Status {
+		case DiffCreate:
+			field.New = value
+		case DiffDelete:
+			field.Old = value
+		default:
+			field.New = value
+			field.Old = value
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func diffStatusJson(status DiffStatus) string {
+	switch status {
+	case DiffCreate:
+		return "create"
+	case DiffDelete:
+		return "delete"
+	case DiffUpdate:
+		return "update"
+	}
+	return "same"
+}
+
 type DiffStatus string
 
 const (
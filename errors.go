@@ -1,6 +1,11 @@
 package rove
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type ErrorSkip struct {
 	Message string
@@ -19,3 +24,127 @@ func SkipReset(err error) error {
 	}
 	return err
 }
+
+const ExitChangesPending = 2
+
+// ErrorExit makes the process exit with Code without printing a message. Err holds the underlying error, if any, for callers embedding rove.
+type ErrorExit struct {
+	Code int
+	Err  error
+}
+
+func (err ErrorExit) Error() string {
+	if err.Err != nil {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("Exited with status %d", err.Code)
+}
+
+func (err ErrorExit) Unwrap() error {
+	return err.Err
+}
+
+// CommandError is returned when a command exits with a non-zero status. Stderr holds the output the command wrote to stderr. ExitCode is -1 when the command did not report a status, such as when it was killed by a signal.
+type CommandError struct {
+	Command  string
+	Err      error
+	ExitCode int
+	Stderr   string
+}
+
+func (err CommandError) Error() string {
+	if stderr := strings.TrimSpace(err.Stderr); stderr != "" {
+		return fmt.Sprintf("failed to run command '%s': %v: %s", err.Command, err.Err, stderr)
+	}
+	return fmt.Sprintf("failed to run command '%s': %v", err.Command, err.Err)
+}
+
+func (err CommandError) Unwrap() error {
+	return err.Err
+}
+
+type ErrorJson struct {
+	Command  string `json:"command,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Message  string `json:"message"`
+	Stderr   string `json:"stderr,omitempty"`
+}
+
+type ErrorOutputJson struct {
+	Error ErrorJson `json:"error"`
+}
+
+func errorJson(err error) ErrorJson {
+	output := ErrorJson{
+		Message: err.Error(),
+	}
+	var errCommand CommandError
+	if errors.As(err, &errCommand) {
+		output.Command = errCommand.Command
+		output.ExitCode = errCommand.ExitCode
+		output.Stderr = errCommand.Stderr
+	}
+	return output
+}
+
+// jsonError prints err to STDOUT as JSON for commands run with --json, and returns an ErrorExit so that the message is not repeated on STDERR.
+func jsonError(enabled bool, err error) error {
+	var errExit ErrorExit
+	if !enabled || err == nil || errors.As(err, &errExit) {
+		return err
+	}
+	output := ErrorOutputJson{
+		Error: errorJson(err),
+	}
+	out, errJson := json.MarshalIndent(output, "", "    ")
+	if errJson != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return ErrorExit{Code: 1, Err: err}
+}
+
+type SshErrorKind string
+
+const (
+	SshErrorAuth        SshErrorKind = "auth"
+	SshErrorHostKey     SshErrorKind = "host_key"
+	SshErrorKey         SshErrorKind = "key"
+	SshErrorTimeout     SshErrorKind = "timeout"
+	SshErrorUnreachable SshErrorKind = "unreachable"
+)
+
+// ErrorSsh is returned when a connection to a machine cannot be established. Match a kind with errors.Is(err, ErrorSsh{Kind: SshErrorAuth}), or any kind with errors.Is(err, ErrorSsh{}).
+type ErrorSsh struct {
+	Address string
+	Err     error
+	Kind    SshErrorKind
+}
+
+func (err ErrorSsh) Error() string {
+	switch err.Kind {
+	case SshErrorAuth:
+		return fmt.Sprintf("🚫 Authentication to '%s' failed: %v", err.Address, err.Err)
+	case SshErrorKey:
+		return fmt.Sprintf("🚫 Unable to use private key '%s': %v", err.Address, err.Err)
+	case SshErrorTimeout:
+		return fmt.Sprintf("🚫 Timed out connecting to '%s': %v", err.Address, err.Err)
+	case SshErrorUnreachable:
+		return fmt.Sprintf("🚫 Unable to reach '%s': %v", err.Address, err.Err)
+	}
+	return err.Err.Error()
+}
+
+func (err ErrorSsh) Is(target error) bool {
+	t, ok := target.(ErrorSsh)
+	return ok && (t.Kind == "" || t.Kind == err.Kind)
+}
+
+// Retryable reports whether the failure may be transient.
+func (err ErrorSsh) Retryable() bool {
+	return err.Kind == SshErrorTimeout || err.Kind == SshErrorUnreachable
+}
+
+func (err ErrorSsh) Unwrap() error {
+	return err.Err
+}
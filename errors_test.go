@@ -0,0 +1,46 @@
+package rove
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJsonError(t *testing.T) {
+	errCommand := CommandError{
+		Command:  "docker service inspect missing",
+		Err:      errors.New("Process exited with status 1"),
+		ExitCode: 1,
+		Stderr:   "Error: no such service: missing\n",
+	}
+	expected := `{
+    "error": {
+        "command": "docker service inspect missing",
+        "exit_code": 1,
+        "message": "failed to run command 'docker service inspect missing': Process exited with status 1: Error: no such service: missing",
+        "stderr": "Error: no such service: missing\n"
+    }
+}
+`
+	capture(t).
+		Run(func() error {
+			err := jsonError(true, errCommand)
+			var errExit ErrorExit
+			if !errors.As(err, &errExit) || errExit.Code != 1 || !errors.Is(err, errCommand) {
+				t.Errorf("'%#v' did not match expected.", err)
+			}
+			return nil
+		}).
+		ExpectStdout(expected)
+
+	capture(t).
+		Run(func() error {
+			if err := jsonError(false, errCommand); !errors.Is(err, errCommand) {
+				t.Errorf("'%#v' did not match expected.", err)
+			}
+			if err := jsonError(true, ErrorExit{Code: ExitChangesPending}); err != (ErrorExit{Code: ExitChangesPending}) {
+				t.Errorf("'%#v' did not match expected.", err)
+			}
+			return nil
+		}).
+		ExpectStdout("")
+}
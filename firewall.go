@@ -0,0 +1,456 @@
+package rove
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alessio/shellescape"
+	"github.com/evantbyrne/trance"
+)
+
+// firewallSwarmComment marks the ufw rules that Rove manages for swarm traffic, so that rules added by hand are left alone.
+const firewallSwarmComment = "rove-swarm"
+
+// firewallSwarmPorts are the ports used by swarm management, gossip and overlay networks.
+var firewallSwarmPorts = []struct {
+	Port  int
+	Proto string
+}{
+	{2377, "tcp"},
+	{7946, "tcp"},
+	{7946, "udp"},
+	{4789, "udp"},
+}
+
+// firewallSwarmRules returns the ufw rules that allow swarm traffic from peers, in the form printed by `ufw show added`.
+func firewallSwarmRules(peers []string) []string {
+	rules := make([]string, 0, len(peers)*len(firewallSwarmPorts))
+	for _, peer := range peers {
+		for _, port := range firewallSwarmPorts {
+			rules = append(rules, fmt.Sprintf("allow from %s to any port %d proto %s", peer, port.Port, port.Proto))
+		}
+	}
+	return rules
+}
+
+// firewallSwarmRulesParse returns the Rove-managed swarm rules from the output of `ufw show added`.
+func firewallSwarmRulesParse(res string) []string {
+	rules := make([]string, 0)
+	suffix := fmt.Sprintf(" comment '%s'", firewallSwarmComment)
+	for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "ufw ") && strings.HasSuffix(line, suffix) {
+			rules = append(rules, strings.TrimSuffix(strings.TrimPrefix(line, "ufw "), suffix))
+		}
+	}
+	return rules
+}
+
+// firewallSwarmBackend holds the commands that manage swarm rules with one kind of firewall.
+type firewallSwarmBackend struct {
+	Add    func(rule string) string
+	Delete func(rule string) string
+	List   string
+	Parse  func(res string) []string
+	Reload string
+	Rules  func(peers []string) []string
+}
+
+var firewallSwarmUfw = firewallSwarmBackend{
+	Add: func(rule string) string {
+		return fmt.Sprintf("sudo ufw %s comment '%s'", rule, firewallSwarmComment)
+	},
+	Delete: func(rule string) string {
+		return fmt.Sprint("sudo ufw delete ", rule)
+	},
+	List:  "sudo ufw show added",
+	Parse: firewallSwarmRulesParse,
+	Rules: firewallSwarmRules,
+}
+
+var firewallSwarmFirewalld = firewallSwarmBackend{
+	Add: func(rule string) string {
+		return fmt.Sprint("sudo firewall-cmd --permanent --add-rich-rule=", shellescape.Quote(rule))
+	},
+	Delete: func(rule string) string {
+		return fmt.Sprint("sudo firewall-cmd --permanent --remove-rich-rule=", shellescape.Quote(rule))
+	},
+	List:   "sudo firewall-cmd --permanent --list-rich-rules",
+	Parse:  firewalldSwarmRulesParse,
+	Reload: "sudo firewall-cmd --reload",
+	Rules:  firewalldSwarmRules,
+}
+
+// firewalldSwarmRule matches the rich rules created by firewalldSwarmRules. Rich rules cannot hold comments, so Rove manages every rule of this form.
+var firewalldSwarmRule = regexp.MustCompile(`^rule family="ipv[46]" source address="[^"]+" port port="(2377|7946|4789)" protocol="(tcp|udp)" accept$`)
+
+// firewalldSwarmRules returns the firewalld rich rules that allow swarm traffic from peers, in the form printed by `firewall-cmd --list-rich-rules`.
+func firewalldSwarmRules(peers []string) []string {
+	rules := make([]string, 0, len(peers)*len(firewallSwarmPorts))
+	for _, peer := range peers {
+		family := "ipv4"
+		if strings.Contains(peer, ":") {
+			family = "ipv6"
+		}
+		for _, port := range firewallSwarmPorts {
+			rules = append(rules, fmt.Sprintf(`rule family="%s" source address="%s" port port="%d" protocol="%s" accept`, family, peer, port.Port, port.Proto))
+		}
+	}
+	return rules
+}
+
+func firewalldSwarmRulesParse(res string) []string {
+	rules := make([]string, 0)
+	for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); firewalldSwarmRule.MatchString(line) {
+			rules = append(rules, line)
+		}
+	}
+	return rules
+}
+
+// firewallSwarmApply makes the Rove-managed swarm rules on a machine match peers, using ufw or firewalld. Machines without either are skipped with a warning.
+func firewallSwarmApply(conn SshRunner, name string, peers []string) error {
+	stdout := conn.Stdout()
+	var backend *firewallSwarmBackend
+	existing := make([]string, 0)
+	err := conn.
+		Run("command -v ufw", func(_ string) error {
+			backend = &firewallSwarmUfw
+			return nil
+		}).
+		OnError(func(error) error {
+			return nil
+		}).
+		Error()
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		err = conn.
+			Run("command -v firewall-cmd", func(_ string) error {
+				backend = &firewallSwarmFirewalld
+				return nil
+			}).
+			OnError(func(error) error {
+				fmt.Fprintf(stdout, "⚠️  Warning: ufw and firewalld missing on '%s'. Swarm ports must be opened to other machines in the cluster manually.\n", name)
+				return ErrorSkip{}
+			}).
+			Error()
+		if err != nil {
+			return SkipReset(err)
+		}
+	}
+	err = conn.
+		Run(backend.List, func(res string) error {
+			existing = backend.Parse(res)
+			return nil
+		}).
+		Error()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	desired := backend.Rules(peers)
+	for _, rule := range existing {
+		if !slices.Contains(desired, rule) {
+			if err := conn.Run(backend.Delete(rule), func(_ string) error {
+				fmt.Fprintf(stdout, "~ Removed firewall rule on '%s': %s\n", name, rule)
+				return nil
+			}).Error(); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+	for _, rule := range desired {
+		if !slices.Contains(existing, rule) {
+			if err := conn.Run(backend.Add(rule), func(_ string) error {
+				fmt.Fprintf(stdout, "~ Added firewall rule on '%s': %s\n", name, rule)
+				return nil
+			}).Error(); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+	if changed && backend.Reload != "" {
+		return conn.Run(backend.Reload, func(_ string) error {
+			return nil
+		}).Error()
+	}
+	return nil
+}
+
+// firewallSwarmSync updates the swarm rules on every machine in cluster to allow traffic from the other machines in it. Machines that are joining the cluster but not yet configured are allowed without their own rules being updated.
+func firewallSwarmSync(ctx context.Context, cluster string, joining ...*Machine) error {
+	machines, peers, err := firewallSwarmPeers(ctx, cluster, joining...)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	for _, machine := range machines {
+		err := SshMachineContext(ctx, machine, func(conn SshRunner, _ io.Reader) error {
+			return firewallSwarmApply(conn, machine.Name, peers[machine.Name])
+		})
+		if err != nil {
+			fmt.Printf("🚫 Could not update firewall on '%s'\n", machine.Name)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// firewallSwarmPeers returns the configured machines in cluster, and for each of them the addresses of the other machines in it, including those that are joining.
+func firewallSwarmPeers(ctx context.Context, cluster string, joining ...*Machine) ([]*Machine, map[string][]string, error) {
+	if cluster == "" {
+		return nil, nil, nil
+	}
+	machines, err := trance.Query[Machine]().Filter("cluster", "=", cluster).Sort("name").All().Collect()
+	if err != nil {
+		return nil, nil, err
+	}
+	addresses := make(map[string][]string)
+	for _, machine := range append(slices.Clone(machines), joining...) {
+		addresses[machine.Name], err = firewallAddresses(ctx, machine)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	peers := make(map[string][]string)
+	for _, machine := range machines {
+		peers[machine.Name] = make([]string, 0)
+		for _, peer := range append(slices.Clone(machines), joining...) {
+			if peer.Name != machine.Name {
+				peers[machine.Name] = append(peers[machine.Name], addresses[peer.Name]...)
+			}
+		}
+	}
+	return machines, peers, nil
+}
+
+// firewallAddresses returns the IP addresses of a machine, since ufw only accepts addresses and not hostnames.
+func firewallAddresses(ctx context.Context, machine *Machine) ([]string, error) {
+	resolved, err := sshConfigResolve(machine)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(resolved.Address); ip != nil {
+		return []string{ip.String()}, nil
+	}
+	addresses, err := net.DefaultResolver.LookupHost(ctx, resolved.Address)
+	if err != nil {
+		return nil, fmt.Errorf("🚫 Could not resolve address of machine '%s': %w", machine.Name, err)
+	}
+	slices.Sort(addresses)
+	return addresses, nil
+}
+
+type FirewallListJson struct {
+	Active   bool               `json:"active"`
+	Firewall string             `json:"firewall"`
+	Rules    []FirewallRuleJson `json:"rules"`
+}
+
+type FirewallRuleJson struct {
+	Action  string `json:"action"`
+	Comment string `json:"comment,omitempty"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+var firewallColumns = regexp.MustCompile(`\s{2,}`)
+
+// firewallStatusParse reads the output of `ufw status`.
+func firewallStatusParse(res string) FirewallListJson {
+	output := FirewallListJson{
+		Firewall: FirewallUfw,
+		Rules:    make([]FirewallRuleJson, 0),
+	}
+	rules := false
+	for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "Status: active":
+			output.Active = true
+		case strings.HasPrefix(line, "--"):
+			rules = true
+		case rules && line != "":
+			comment := ""
+			if i := strings.Index(line, "# "); i >= 0 {
+				comment = strings.TrimSpace(line[i+2:])
+				line = strings.TrimSpace(line[:i])
+			}
+			columns := firewallColumns.Split(line, -1)
+			if len(columns) < 3 {
+				continue
+			}
+			output.Rules = append(output.Rules, FirewallRuleJson{
+				Action:  columns[1],
+				Comment: comment,
+				From:    columns[2],
+				To:      columns[0],
+			})
+		}
+	}
+	return output
+}
+
+// firewalldRichRule matches the rich rules that firewalldStatusParse reports, which are those that open or close ports.
+var firewalldRichRule = regexp.MustCompile(`^rule (?:family="ipv[46]" )?(?:source address="([^"]+)" )?port port="([0-9]+(?:-[0-9]+)?)" protocol="(tcp|udp)" (accept|reject|drop)$`)
+
+// firewalldActions maps the actions of rich rules to the names ufw uses.
+var firewalldActions = map[string]string{
+	"accept": "ALLOW",
+	"drop":   "DENY",
+	"reject": "REJECT",
+}
+
+// firewalldStatusParse reads the services, ports and rich rules of the zone printed by `firewall-cmd --list-all`. Ranges of ports are written with a colon, as ufw does.
+func firewalldStatusParse(res string) []FirewallRuleJson {
+	rules := make([]FirewallRuleJson, 0)
+	for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		key, value, _ := strings.Cut(line, ":")
+		switch {
+		case strings.HasPrefix(line, "rule "):
+			match := firewalldRichRule.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			rule := FirewallRuleJson{
+				Action: firewalldActions[match[4]],
+				From:   cmp.Or(match[1], "Anywhere"),
+				To:     fmt.Sprintf("%s/%s", strings.ReplaceAll(match[2], "-", ":"), match[3]),
+			}
+			if firewalldSwarmRule.MatchString(line) {
+				rule.Comment = firewallSwarmComment
+			}
+			rules = append(rules, rule)
+		case key == "services" || key == "ports":
+			for _, to := range strings.Fields(value) {
+				if key == "ports" {
+					to = strings.ReplaceAll(to, "-", ":")
+				}
+				rules = append(rules, FirewallRuleJson{
+					Action: "ALLOW",
+					From:   "Anywhere",
+					To:     to,
+				})
+			}
+		}
+	}
+	return rules
+}
+
+// firewallDetect chooses the firewall of the machine, and fails when it is not one that Rove manages.
+func firewallDetect(conn SshRunner) (*provisioner, error) {
+	var release OsRelease
+	err := conn.
+		Run("cat /etc/os-release", func(res string) error {
+			release = osReleaseParse(res)
+			return nil
+		}).
+		Error()
+	if err != nil {
+		return nil, err
+	}
+	p, err := provisionerDetect(conn, release)
+	if err != nil {
+		return nil, err
+	}
+	if p.Firewall == "" {
+		return nil, fmt.Errorf("🚫 Unsupported firewall on OS '%s'. Rove manages ufw and firewalld", release)
+	}
+	return p, nil
+}
+
+// firewallStatus reads whether the firewall is enabled and its rules.
+func firewallStatus(conn SshRunner, p *provisioner) (FirewallListJson, error) {
+	var status FirewallListJson
+	if p.Firewall != FirewallFirewalld {
+		err := conn.
+			Run("sudo ufw status", func(res string) error {
+				status = firewallStatusParse(res)
+				return nil
+			}).
+			Error()
+		return status, err
+	}
+
+	status = FirewallListJson{
+		Firewall: FirewallFirewalld,
+		Rules:    make([]FirewallRuleJson, 0),
+	}
+	statusCommand, statusActive := p.FirewallStatus()
+	err := conn.
+		Run(statusCommand, func(res string) error {
+			status.Active = statusActive(res)
+			return nil
+		}).
+		OnError(func(error) error {
+			// firewall-cmd exits with an error when firewalld is not running, and rules cannot be listed without it, like `ufw status` when inactive.
+			return ErrorSkip{}
+		}).
+		Run("sudo firewall-cmd --list-all", func(res string) error {
+			status.Rules = firewalldStatusParse(res)
+			return nil
+		}).
+		Error()
+	return status, SkipReset(err)
+}
+
+// firewallRuleHasPort reports whether a rule names ports, rather than a service or application profile.
+func firewallRuleHasPort(rule FirewallRuleJson) bool {
+	to := strings.Fields(strings.TrimSuffix(rule.To, " (v6)"))
+	if len(to) == 0 {
+		return false
+	}
+	ports, _, _ := strings.Cut(to[len(to)-1], "/")
+	for _, entry := range strings.Split(ports, ",") {
+		if !firewallPort.MatchString(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// firewallRuleMatches reports whether a ufw rule applies to traffic from anywhere to port over proto.
+func firewallRuleMatches(rule FirewallRuleJson, port int64, proto string) bool {
+	if !strings.HasPrefix(rule.From, "Anywhere") {
+		return false
+	}
+	to := strings.Fields(strings.TrimSuffix(rule.To, " (v6)"))
+	if len(to) == 0 {
+		return false
+	}
+	spec := to[len(to)-1]
+	if spec == "Anywhere" {
+		return true
+	}
+	ports, ruleProto, found := strings.Cut(spec, "/")
+	if found && ruleProto != proto {
+		return false
+	}
+	for _, entry := range strings.Split(ports, ",") {
+		low, high, isRange := strings.Cut(entry, ":")
+		if !isRange {
+			high = low
+		}
+		lowPort, errLow := strconv.ParseInt(low, 10, 64)
+		highPort, errHigh := strconv.ParseInt(high, 10, 64)
+		if errLow == nil && errHigh == nil && port >= lowPort && port <= highPort {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package rove
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type FirewallAuditCommand struct {
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+
+	sshPort int64
+}
+
+type FirewallAuditJson struct {
+	Active     bool                   `json:"active"`
+	Mismatches []FirewallMismatchJson `json:"mismatches"`
+}
+
+type FirewallMismatchJson struct {
+	Message  string   `json:"message"`
+	Port     string   `json:"port,omitempty"`
+	Services []string `json:"services,omitempty"`
+	Type     string   `json:"type"`
+}
+
+const (
+	FirewallMismatchInactive            = "inactive"
+	FirewallMismatchAllowedNotPublished = "allowed_not_published"
+	FirewallMismatchPublishedNotAllowed = "published_not_allowed"
+)
+
+type firewallPublished struct {
+	Port     int64
+	Proto    string
+	Services []string
+}
+
+func (cmd *FirewallAuditCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	names := make([]string, 0)
+	published := make([]*firewallPublished, 0)
+	p, err := firewallDetect(conn)
+	if err != nil {
+		return err
+	}
+	status, err := firewallStatus(conn, p)
+	if err != nil {
+		return err
+	}
+	err = conn.
+		Run("docker service ls --format json --filter label=rove", func(res string) error {
+			for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+				if line != "" {
+					var dockerServiceLs DockerServiceLsJson
+					if err := json.Unmarshal([]byte(line), &dockerServiceLs); err != nil {
+						fmt.Fprintln(stdout, "🚫 Could not parse docker service ls JSON:\n", line)
+						return err
+					}
+					names = append(names, dockerServiceLs.Name)
+				}
+			}
+			return nil
+		}).
+		Error()
+	if err != nil {
+		return err
+	}
+	err = dockerServiceInspectAll(conn, names, func(dockerInspect []DockerServiceInspectJson) error {
+		for i, inspect := range dockerInspect {
+			for _, entry := range inspect.Spec.EndpointSpec.Ports {
+				if entry.PublishedPort == 0 {
+					continue
+				}
+				index := slices.IndexFunc(published, func(p *firewallPublished) bool {
+					return p.Port == entry.PublishedPort && p.Proto == entry.Protocol
+				})
+				if index < 0 {
+					published = append(published, &firewallPublished{Port: entry.PublishedPort, Proto: entry.Protocol})
+					index = len(published) - 1
+				}
+				published[index].Services = append(published[index].Services, names[i])
+			}
+		}
+		return nil
+	}).Error()
+	if err != nil {
+		return err
+	}
+
+	output := firewallAudit(status, published, cmp.Or(cmd.sshPort, 22))
+	if cmd.Json {
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+	} else if len(output.Mismatches) == 0 {
+		fmt.Fprintln(stdout, "✅ Firewall rules match published ports")
+	} else {
+		for _, mismatch := range output.Mismatches {
+			fmt.Fprintln(stdout, "🚫", mismatch.Message)
+		}
+	}
+	if len(output.Mismatches) > 0 {
+		return ErrorExit{Code: 1}
+	}
+	return nil
+}
+
+// firewallAudit compares ufw or firewalld rules against published ports. Docker publishes ports through iptables rules that bypass the firewall, so ports may be reachable even though the firewall does not list them.
+func firewallAudit(status FirewallListJson, published []*firewallPublished, sshPort int64) FirewallAuditJson {
+	firewall := cmp.Or(status.Firewall, FirewallUfw)
+	output := FirewallAuditJson{
+		Active:     status.Active,
+		Mismatches: make([]FirewallMismatchJson, 0),
+	}
+	if !status.Active {
+		output.Mismatches = append(output.Mismatches, FirewallMismatchJson{
+			Message: "Firewall is inactive",
+			Type:    FirewallMismatchInactive,
+		})
+	}
+
+	for _, entry := range published {
+		allowed := slices.ContainsFunc(status.Rules, func(rule FirewallRuleJson) bool {
+			return strings.HasPrefix(rule.Action, "ALLOW") && firewallRuleMatches(rule, entry.Port, entry.Proto)
+		})
+		if !allowed {
+			port := fmt.Sprintf("%d/%s", entry.Port, entry.Proto)
+			output.Mismatches = append(output.Mismatches, FirewallMismatchJson{
+				Message:  fmt.Sprintf("Port %s is published by %s but not allowed by %s. Docker bypasses %s, so it is reachable anyway", port, strings.Join(entry.Services, ", "), firewall, firewall),
+				Port:     port,
+				Services: entry.Services,
+				Type:     FirewallMismatchPublishedNotAllowed,
+			})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range status.Rules {
+		to := strings.TrimSuffix(rule.To, " (v6)")
+		if !strings.HasPrefix(rule.Action, "ALLOW") || rule.Comment == firewallSwarmComment || !firewallRuleHasPort(rule) || seen[to] {
+			continue
+		}
+		seen[to] = true
+		if firewallRuleMatches(rule, sshPort, "tcp") {
+			continue
+		}
+		used := slices.ContainsFunc(published, func(entry *firewallPublished) bool {
+			return firewallRuleMatches(rule, entry.Port, entry.Proto)
+		})
+		if !used && strings.HasPrefix(rule.From, "Anywhere") {
+			output.Mismatches = append(output.Mismatches, FirewallMismatchJson{
+				Message: fmt.Sprintf("Port %s is allowed by %s but not published by any service", to, firewall),
+				Port:    to,
+				Type:    FirewallMismatchAllowedNotPublished,
+			})
+		}
+	}
+	return output
+}
+
+func (cmd *FirewallAuditCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		if !cmd.Local {
+			// The SSH port is expected to be allowed, so it is not reported. Machines that use an SSH config alias may connect on another port.
+			if machine, err := machineByName(cmd.Machine); err == nil {
+				resolved, err := sshConfigResolve(machine)
+				if err != nil {
+					return err
+				}
+				cmd.sshPort = resolved.Port
+			}
+		}
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	}))
+}
@@ -0,0 +1,62 @@
+package rove
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFirewallAudit(t *testing.T) {
+	published := []*firewallPublished{
+		{Port: 443, Proto: "tcp", Services: []string{"web"}},
+		{Port: 8443, Proto: "tcp", Services: []string{"admin", "api"}},
+	}
+	output := firewallAudit(firewallStatusParse(firewallStatusTest), published, 22)
+	expected := []FirewallMismatchJson{
+		{
+			Message:  "Port 8443/tcp is published by admin, api but not allowed by ufw. Docker bypasses ufw, so it is reachable anyway",
+			Port:     "8443/tcp",
+			Services: []string{"admin", "api"},
+			Type:     FirewallMismatchPublishedNotAllowed,
+		},
+		{
+			Message: "Port 8000:8100/tcp is allowed by ufw but not published by any service",
+			Port:    "8000:8100/tcp",
+			Type:    FirewallMismatchAllowedNotPublished,
+		},
+	}
+	if len(output.Mismatches) != len(expected) {
+		t.Fatalf("'%#v' did not match expected.", output.Mismatches)
+	}
+	for i := range expected {
+		if output.Mismatches[i].Message != expected[i].Message || output.Mismatches[i].Type != expected[i].Type || !slices.Equal(output.Mismatches[i].Services, expected[i].Services) {
+			t.Errorf("'%#v' did not match expected '%#v'.", output.Mismatches[i], expected[i])
+		}
+	}
+
+	status := FirewallListJson{Active: true, Firewall: FirewallFirewalld, Rules: firewalldStatusParse(firewalldStatusTest)}
+	output = firewallAudit(status, published, 22)
+	expected = []FirewallMismatchJson{
+		{
+			Message:  "Port 8443/tcp is published by admin, api but not allowed by firewalld. Docker bypasses firewalld, so it is reachable anyway",
+			Services: []string{"admin", "api"},
+			Type:     FirewallMismatchPublishedNotAllowed,
+		},
+		{
+			Message: "Port 8000:8100/tcp is allowed by firewalld but not published by any service",
+			Type:    FirewallMismatchAllowedNotPublished,
+		},
+	}
+	if len(output.Mismatches) != len(expected) {
+		t.Fatalf("'%#v' did not match expected.", output.Mismatches)
+	}
+	for i := range expected {
+		if output.Mismatches[i].Message != expected[i].Message || output.Mismatches[i].Type != expected[i].Type || !slices.Equal(output.Mismatches[i].Services, expected[i].Services) {
+			t.Errorf("'%#v' did not match expected '%#v'.", output.Mismatches[i], expected[i])
+		}
+	}
+
+	output = firewallAudit(FirewallListJson{}, nil, 22)
+	if len(output.Mismatches) != 1 || output.Mismatches[0].Type != FirewallMismatchInactive {
+		t.Errorf("'%#v' did not report inactive firewall.", output.Mismatches)
+	}
+}
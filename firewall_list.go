@@ -0,0 +1,50 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type FirewallListCommand struct {
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+}
+
+func (cmd *FirewallListCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	p, err := firewallDetect(conn)
+	if err != nil {
+		return err
+	}
+	output, err := firewallStatus(conn, p)
+	if err != nil {
+		return err
+	}
+	if cmd.Json {
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+		return nil
+	}
+	if !output.Active {
+		fmt.Fprintln(stdout, "Firewall inactive")
+		return nil
+	}
+	for _, rule := range output.Rules {
+		fmt.Fprintln(stdout, rule.To, rule.Action, rule.From, rule.Comment)
+	}
+	return nil
+}
+
+func (cmd *FirewallListCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	}))
+}
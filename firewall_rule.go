@@ -0,0 +1,147 @@
+package rove
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/alessio/shellescape"
+)
+
+var firewallPort = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+type FirewallAllowCommand struct {
+	Port string `arg:"" name:"port" help:"Port or range of ports, such as 443 or 8000:8100."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	From       string `flag:"" name:"from" help:"Only match traffic from address or subnet."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	Proto      string `flag:"" name:"proto" help:"Protocol. Either tcp, udp or any." enum:"tcp,udp,any" default:"tcp"`
+}
+
+func (cmd *FirewallAllowCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return firewallRuleAdd(conn, stdin, cmd.Force, "allow", cmd.Port, cmd.Proto, cmd.From)
+}
+
+func (cmd *FirewallAllowCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	})
+}
+
+type FirewallDenyCommand struct {
+	Port string `arg:"" name:"port" help:"Port or range of ports, such as 443 or 8000:8100."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	From       string `flag:"" name:"from" help:"Only match traffic from address or subnet."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	Proto      string `flag:"" name:"proto" help:"Protocol. Either tcp, udp or any." enum:"tcp,udp,any" default:"tcp"`
+}
+
+func (cmd *FirewallDenyCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return firewallRuleAdd(conn, stdin, cmd.Force, "deny", cmd.Port, cmd.Proto, cmd.From)
+}
+
+func (cmd *FirewallDenyCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	})
+}
+
+// firewallRuleCommands builds the ufw or firewalld commands for action on port. Ranges of ports require a protocol.
+func firewallRuleCommands(firewall string, action string, port string, proto string, from string) ([]string, error) {
+	if !firewallPort.MatchString(port) {
+		return nil, fmt.Errorf("🚫 Invalid port '%s'", port)
+	}
+	if proto == "any" && strings.Contains(port, ":") {
+		return nil, fmt.Errorf("🚫 Ranges of ports require --proto tcp or --proto udp")
+	}
+	if from != "" {
+		if _, _, err := net.ParseCIDR(from); err != nil && net.ParseIP(from) == nil {
+			return nil, fmt.Errorf("🚫 Invalid address '%s'", from)
+		}
+	}
+	if firewall == FirewallFirewalld {
+		return firewalldRuleCommands(action, port, proto, from), nil
+	}
+	if from != "" {
+		command := fmt.Sprintf("sudo ufw %s from %s to any port %s", action, shellescape.Quote(from), port)
+		if proto != "any" {
+			command = fmt.Sprint(command, " proto ", proto)
+		}
+		return []string{command}, nil
+	}
+	if proto != "any" {
+		return []string{fmt.Sprintf("sudo ufw %s %s/%s", action, port, proto)}, nil
+	}
+	return []string{fmt.Sprintf("sudo ufw %s %s", action, port)}, nil
+}
+
+// firewalldRuleCommands opens ports in the default zone, and uses rich rules to deny ports or to match an address. Both require a protocol, so "any" adds rules for tcp and udp.
+func firewalldRuleCommands(action string, port string, proto string, from string) []string {
+	port = strings.ReplaceAll(port, ":", "-")
+	protos := []string{proto}
+	if proto == "any" {
+		protos = []string{"tcp", "udp"}
+	}
+	args := make([]string, 0, len(protos))
+	for _, proto := range protos {
+		if action == "allow" && from == "" {
+			args = append(args, fmt.Sprintf("--add-port=%s/%s", port, proto))
+			continue
+		}
+		rule := fmt.Sprintf(`rule port port="%s" protocol="%s" %s`, port, proto, ternary(action == "allow", "accept", "drop"))
+		if from != "" {
+			rule = fmt.Sprintf(`rule family="%s" source address="%s" %s`, ternary(strings.Contains(from, ":"), "ipv6", "ipv4"), from, strings.TrimPrefix(rule, "rule "))
+		}
+		args = append(args, fmt.Sprint("--add-rich-rule=", shellescape.Quote(rule)))
+	}
+	return []string{
+		fmt.Sprint("sudo firewall-cmd --permanent ", strings.Join(args, " ")),
+		"sudo firewall-cmd --reload",
+	}
+}
+
+func firewallRuleAdd(conn SshRunner, stdin io.Reader, force bool, action string, port string, proto string, from string) error {
+	stdout := conn.Stdout()
+	p, err := firewallDetect(conn)
+	if err != nil {
+		return err
+	}
+	commands, err := firewallRuleCommands(p.Firewall, action, port, proto, from)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "\nRove will run '%s'.\n", strings.Join(commands, "' and '"))
+	if action == "deny" {
+		fmt.Fprintf(stdout, "Note that ports published by Docker services bypass %s and are not blocked by this rule.\n", p.Firewall)
+	}
+	if err := confirmDeploymentTo(stdout, force, stdin); err != nil {
+		return err
+	}
+	for _, command := range commands {
+		err := conn.
+			Run(command, func(res string) error {
+				fmt.Fprintf(stdout, "\nUpdated firewall: %s\n\n", strings.TrimSpace(res))
+				return nil
+			}).
+			OnError(func(err error) error {
+				if err != nil {
+					fmt.Fprintln(stdout, "🚫 Could not update firewall")
+				}
+				return err
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
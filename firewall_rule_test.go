@@ -0,0 +1,62 @@
+package rove
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFirewallRuleCommand(t *testing.T) {
+	tests := []struct {
+		port     string
+		proto    string
+		from     string
+		expected string
+	}{
+		{"443", "tcp", "", "sudo ufw allow 443/tcp"},
+		{"443", "any", "", "sudo ufw allow 443"},
+		{"8000:8100", "udp", "", "sudo ufw allow 8000:8100/udp"},
+		{"5432", "tcp", "10.0.0.0/8", "sudo ufw allow from 10.0.0.0/8 to any port 5432 proto tcp"},
+		{"5432", "any", "10.0.0.1", "sudo ufw allow from 10.0.0.1 to any port 5432"},
+	}
+	for _, test := range tests {
+		commands, err := firewallRuleCommands(FirewallUfw, "allow", test.port, test.proto, test.from)
+		if err != nil {
+			t.Error(err)
+		} else if !slices.Equal(commands, []string{test.expected}) {
+			t.Errorf("'%#v' did not match expected '%s'.", commands, test.expected)
+		}
+	}
+	for _, test := range [][3]string{{"443; reboot", "tcp", ""}, {"8000:8100", "any", ""}, {"443", "tcp", "example.com"}} {
+		for _, firewall := range []string{FirewallUfw, FirewallFirewalld} {
+			if _, err := firewallRuleCommands(firewall, "allow", test[0], test[1], test[2]); err == nil {
+				t.Errorf("expected '%#v' to be invalid for %s.", test, firewall)
+			}
+		}
+	}
+}
+
+func TestFirewallRuleCommandsFirewalld(t *testing.T) {
+	tests := []struct {
+		action   string
+		port     string
+		proto    string
+		from     string
+		expected string
+	}{
+		{"allow", "443", "tcp", "", "sudo firewall-cmd --permanent --add-port=443/tcp"},
+		{"allow", "53", "any", "", "sudo firewall-cmd --permanent --add-port=53/tcp --add-port=53/udp"},
+		{"allow", "8000:8100", "udp", "", "sudo firewall-cmd --permanent --add-port=8000-8100/udp"},
+		{"allow", "5432", "tcp", "10.0.0.0/8", `sudo firewall-cmd --permanent --add-rich-rule='rule family="ipv4" source address="10.0.0.0/8" port port="5432" protocol="tcp" accept'`},
+		{"deny", "5432", "tcp", "", `sudo firewall-cmd --permanent --add-rich-rule='rule port port="5432" protocol="tcp" drop'`},
+		{"deny", "5432", "tcp", "2001:db8::1", `sudo firewall-cmd --permanent --add-rich-rule='rule family="ipv6" source address="2001:db8::1" port port="5432" protocol="tcp" drop'`},
+	}
+	for _, test := range tests {
+		commands, err := firewallRuleCommands(FirewallFirewalld, test.action, test.port, test.proto, test.from)
+		expected := []string{test.expected, "sudo firewall-cmd --reload"}
+		if err != nil {
+			t.Error(err)
+		} else if !slices.Equal(commands, expected) {
+			t.Errorf("'%#v' did not match expected '%#v'.", commands, expected)
+		}
+	}
+}
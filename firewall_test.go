@@ -0,0 +1,249 @@
+package rove
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/evantbyrne/trance"
+)
+
+func TestFirewallSwarmRulesParse(t *testing.T) {
+	res := "Added user rules (see 'ufw status' for running firewall):\n" +
+		"ufw allow 22/tcp\n" +
+		"ufw allow from 192.0.2.2 to any port 2377 proto tcp comment 'rove-swarm'\n" +
+		"ufw allow from 192.0.2.9 to any port 2377 proto tcp comment 'other'\n"
+	expected := []string{"allow from 192.0.2.2 to any port 2377 proto tcp"}
+	if rules := firewallSwarmRulesParse(res); !slices.Equal(rules, expected) {
+		t.Errorf("'%#v' did not match expected.", rules)
+	}
+}
+
+func TestFirewallSwarmApply(t *testing.T) {
+	mock := &SshConnectionMock{
+		Result: "Added user rules (see 'ufw status' for running firewall):\n" +
+			"ufw allow from 192.0.2.2 to any port 2377 proto tcp comment 'rove-swarm'\n" +
+			"ufw allow from 192.0.2.2 to any port 7946 proto tcp comment 'rove-swarm'\n" +
+			"ufw allow from 192.0.2.2 to any port 7946 proto udp comment 'rove-swarm'\n" +
+			"ufw allow from 192.0.2.2 to any port 4789 proto udp comment 'rove-swarm'\n" +
+			"ufw allow from 192.0.2.3 to any port 2377 proto tcp comment 'rove-swarm'\n",
+	}
+	capture(t).Run(func() error {
+		return firewallSwarmApply(mock, "manager", []string{"192.0.2.2", "192.0.2.4"})
+	})
+	expected := []string{
+		"command -v ufw",
+		"sudo ufw show added",
+		"sudo ufw delete allow from 192.0.2.3 to any port 2377 proto tcp",
+		"sudo ufw allow from 192.0.2.4 to any port 2377 proto tcp comment 'rove-swarm'",
+		"sudo ufw allow from 192.0.2.4 to any port 7946 proto tcp comment 'rove-swarm'",
+		"sudo ufw allow from 192.0.2.4 to any port 7946 proto udp comment 'rove-swarm'",
+		"sudo ufw allow from 192.0.2.4 to any port 4789 proto udp comment 'rove-swarm'",
+	}
+	if !slices.Equal(mock.CommandsRun, expected) {
+		t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+	}
+
+	mock = &SshConnectionMock{
+		Errors: map[string]error{
+			"command -v ufw":          errors.New("not found"),
+			"command -v firewall-cmd": errors.New("not found"),
+		},
+	}
+	capture(t).Run(func() error {
+		return firewallSwarmApply(mock, "manager", []string{"192.0.2.2"})
+	}).ExpectStdout("⚠️  Warning: ufw and firewalld missing on 'manager'. Swarm ports must be opened to other machines in the cluster manually.\n")
+}
+
+func TestFirewallSwarmApplyFirewalld(t *testing.T) {
+	mock := &SshConnectionMock{
+		Errors: map[string]error{
+			"command -v ufw": errors.New("not found"),
+		},
+		Result: "rule family=\"ipv4\" source address=\"192.0.2.3\" port port=\"2377\" protocol=\"tcp\" accept\n" +
+			"rule family=\"ipv4\" source address=\"10.0.0.1\" port port=\"22\" protocol=\"tcp\" accept\n",
+	}
+	capture(t).Run(func() error {
+		return firewallSwarmApply(mock, "worker", []string{"2001:db8::2"})
+	})
+	expected := []string{
+		"command -v ufw",
+		"command -v firewall-cmd",
+		"sudo firewall-cmd --permanent --list-rich-rules",
+		`sudo firewall-cmd --permanent --remove-rich-rule='rule family="ipv4" source address="192.0.2.3" port port="2377" protocol="tcp" accept'`,
+		`sudo firewall-cmd --permanent --add-rich-rule='rule family="ipv6" source address="2001:db8::2" port port="2377" protocol="tcp" accept'`,
+		`sudo firewall-cmd --permanent --add-rich-rule='rule family="ipv6" source address="2001:db8::2" port port="7946" protocol="tcp" accept'`,
+		`sudo firewall-cmd --permanent --add-rich-rule='rule family="ipv6" source address="2001:db8::2" port port="7946" protocol="udp" accept'`,
+		`sudo firewall-cmd --permanent --add-rich-rule='rule family="ipv6" source address="2001:db8::2" port port="4789" protocol="udp" accept'`,
+		"sudo firewall-cmd --reload",
+	}
+	if !slices.Equal(mock.CommandsRun, expected) {
+		t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+	}
+}
+
+const firewallStatusTest = `Status: active
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW       Anywhere
+443/tcp                    ALLOW       Anywhere
+8000:8100/tcp              ALLOW       Anywhere
+2377/tcp                   ALLOW       192.0.2.2                  # rove-swarm
+5432/tcp                   DENY        Anywhere
+22/tcp (v6)                ALLOW       Anywhere (v6)
+`
+
+func TestFirewallStatusParse(t *testing.T) {
+	status := firewallStatusParse(firewallStatusTest)
+	if !status.Active {
+		t.Error("expected firewall to be active.")
+	}
+	expected := []FirewallRuleJson{
+		{Action: "ALLOW", From: "Anywhere", To: "22/tcp"},
+		{Action: "ALLOW", From: "Anywhere", To: "443/tcp"},
+		{Action: "ALLOW", From: "Anywhere", To: "8000:8100/tcp"},
+		{Action: "ALLOW", Comment: "rove-swarm", From: "192.0.2.2", To: "2377/tcp"},
+		{Action: "DENY", From: "Anywhere", To: "5432/tcp"},
+		{Action: "ALLOW", From: "Anywhere (v6)", To: "22/tcp (v6)"},
+	}
+	if !slices.Equal(status.Rules, expected) {
+		t.Errorf("'%#v' did not match expected.", status.Rules)
+	}
+	if firewallStatusParse("Status: inactive\n").Active {
+		t.Error("expected firewall to be inactive.")
+	}
+}
+
+func TestFirewallRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule     FirewallRuleJson
+		port     int64
+		proto    string
+		expected bool
+	}{
+		{FirewallRuleJson{From: "Anywhere", To: "443/tcp"}, 443, "tcp", true},
+		{FirewallRuleJson{From: "Anywhere", To: "443/tcp"}, 443, "udp", false},
+		{FirewallRuleJson{From: "Anywhere", To: "443"}, 443, "udp", true},
+		{FirewallRuleJson{From: "Anywhere", To: "8000:8100/tcp"}, 8080, "tcp", true},
+		{FirewallRuleJson{From: "Anywhere", To: "80,443/tcp"}, 80, "tcp", true},
+		{FirewallRuleJson{From: "192.0.2.2", To: "443/tcp"}, 443, "tcp", false},
+		{FirewallRuleJson{From: "Anywhere (v6)", To: "443/tcp (v6)"}, 443, "tcp", true},
+	}
+	for _, test := range tests {
+		if actual := firewallRuleMatches(test.rule, test.port, test.proto); actual != test.expected {
+			t.Errorf("'%#v' on %d/%s returned %v.", test.rule, test.port, test.proto, actual)
+		}
+	}
+}
+
+func TestFirewallSwarmPeersRename(t *testing.T) {
+	if err := testDatabase(func() error {
+		cluster := clusterNew()
+		for _, machine := range []*Machine{
+			{Address: "192.0.2.1", Cluster: cluster, Name: "manager", Port: 22, User: "root"},
+			{Address: "192.0.2.2", Cluster: cluster, Name: "worker", Port: 22, User: "root"},
+		} {
+			if err := trance.Query[Machine]().Insert(machine).Error; err != nil {
+				return err
+			}
+		}
+		defer trance.Query[Machine]().Delete()
+
+		capture(t).Run(func() error {
+			return (&MachineEditCommand{Name: "manager", Rename: "old-manager"}).Do(context.Background())
+		})
+		// A new machine added under the old name of the manager forms its own cluster.
+		replacement := &Machine{Address: "192.0.2.3", Cluster: clusterNew(), Name: "manager", Port: 22, User: "root"}
+		if replacement.Cluster == cluster {
+			t.Fatal("expected a new cluster ID.")
+		}
+		if err := trance.Query[Machine]().Insert(replacement).Error; err != nil {
+			return err
+		}
+
+		_, peers, err := firewallSwarmPeers(context.Background(), cluster)
+		if err != nil {
+			return err
+		}
+		expected := map[string][]string{
+			"old-manager": {"192.0.2.2"},
+			"worker":      {"192.0.2.1"},
+		}
+		if mustMarshal(peers) != mustMarshal(expected) {
+			t.Errorf("'%s' did not match expected '%s'.", mustMarshal(peers), mustMarshal(expected))
+		}
+
+		_, peers, err = firewallSwarmPeers(context.Background(), replacement.Cluster)
+		if err != nil {
+			return err
+		}
+		expected = map[string][]string{
+			"manager": {},
+		}
+		if mustMarshal(peers) != mustMarshal(expected) {
+			t.Errorf("'%s' did not match expected '%s'.", mustMarshal(peers), mustMarshal(expected))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const firewalldStatusTest = `public (active)
+  target: default
+  icmp-block-inversion: no
+  interfaces: eth0
+  sources: 
+  services: dhcpv6-client ssh
+  ports: 443/tcp 8000-8100/tcp
+  protocols: 
+  forward: yes
+  masquerade: no
+  forward-ports: 
+  source-ports: 
+  icmp-blocks: 
+  rich rules: 
+	rule family="ipv4" source address="192.0.2.2" port port="2377" protocol="tcp" accept
+	rule port port="5432" protocol="tcp" drop
+`
+
+func TestFirewalldStatusParse(t *testing.T) {
+	expected := []FirewallRuleJson{
+		{Action: "ALLOW", From: "Anywhere", To: "dhcpv6-client"},
+		{Action: "ALLOW", From: "Anywhere", To: "ssh"},
+		{Action: "ALLOW", From: "Anywhere", To: "443/tcp"},
+		{Action: "ALLOW", From: "Anywhere", To: "8000:8100/tcp"},
+		{Action: "ALLOW", Comment: firewallSwarmComment, From: "192.0.2.2", To: "2377/tcp"},
+		{Action: "DENY", From: "Anywhere", To: "5432/tcp"},
+	}
+	if rules := firewalldStatusParse(firewalldStatusTest); !slices.Equal(rules, expected) {
+		t.Errorf("'%#v' did not match expected.", rules)
+	}
+}
+
+func TestFirewallDetect(t *testing.T) {
+	conn := &SshConnectionMock{
+		Result: "ID=fedora\nVERSION_ID=41\n",
+	}
+	p, err := firewallDetect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Firewall != FirewallFirewalld {
+		t.Errorf("'%s' did not match expected firewall.", p.Firewall)
+	}
+
+	conn = &SshConnectionMock{
+		Errors: map[string]error{
+			"command -v ufw":          errors.New("exit status 1"),
+			"command -v firewall-cmd": errors.New("exit status 1"),
+		},
+		Result: "ID=arch\n",
+	}
+	if _, err := firewallDetect(conn); err == nil || !strings.Contains(err.Error(), "Unsupported firewall") {
+		t.Errorf("expected unsupported firewall error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package rove
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/evantbyrne/trance"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+// hostKeyTrust records the fingerprint of the host key presented on first connection, optionally verifying it against a known_hosts file.
+func hostKeyTrust(knownHostsFile string, fingerprint *string) (ssh.HostKeyCallback, error) {
+	verify := ssh.InsecureIgnoreHostKey()
+	if knownHostsFile != "" {
+		var err error
+		verify, err = knownhosts.New(knownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read known hosts file: %v", err)
+		}
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := verify(hostname, remote, key); err != nil {
+			return err
+		}
+		*fingerprint = ssh.FingerprintSHA256(key)
+		return nil
+	}, nil
+}
+
+func hostKeyVerify(machine *Machine) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if machine.HostKeyFingerprint == "" {
+			return hostKeyFirstUse(machine, ssh.FingerprintSHA256(key), os.Stdin, term.IsTerminal(int(os.Stdin.Fd())))
+		}
+		if fingerprint := ssh.FingerprintSHA256(key); fingerprint != machine.HostKeyFingerprint {
+			return fmt.Errorf("🚫 Host key for machine '%s' changed from %s to %s. If this is expected, run `rove machine rekey %s`", machine.Name, machine.HostKeyFingerprint, fingerprint, machine.Name)
+		}
+		return nil
+	}
+}
+
+// hostKeyFirstUseLock keeps prompts for machines connected to in parallel from interleaving.
+var hostKeyFirstUseLock sync.Mutex
+
+// hostKeyFirstUse trusts and records the host key of machines that were added before Rove recorded host keys, after confirmation. Without a terminal to confirm on, the machine must be rekeyed.
+func hostKeyFirstUse(machine *Machine, fingerprint string, stdin io.Reader, interactive bool) error {
+	if !interactive {
+		return fmt.Errorf("🚫 No host key recorded for machine '%s'. Run `rove machine rekey %s` to record it, or `rove machine rekey --force %s` in non-interactive environments", machine.Name, machine.Name, machine.Name)
+	}
+	hostKeyFirstUseLock.Lock()
+	defer hostKeyFirstUseLock.Unlock()
+	fmt.Fprintf(os.Stderr, "\nNo host key recorded for machine '%s'.", machine.Name)
+	if err := confirmHostKey(machine.Address, fingerprint, "", false, stdin); err != nil {
+		return err
+	}
+	err := trance.Query[Machine]().
+		Filter("name", "=", machine.Name).
+		UpdateMap(map[string]any{"host_key_fingerprint": fingerprint}).
+		Error
+	if err != nil {
+		return fmt.Errorf("unable to record host key for machine '%s': %v", machine.Name, err)
+	}
+	machine.HostKeyFingerprint = fingerprint
+	fmt.Fprintf(os.Stderr, "✅ Recorded host key for machine '%s'\n\n", machine.Name)
+	return nil
+}
+
+// confirmHostKey prompts on stderr so that output meant for scripts, such as --json, is not mixed with the prompt.
+func confirmHostKey(address string, fingerprint string, knownHostsFile string, force bool, stdin io.Reader) error {
+	fmt.Fprintf(os.Stderr, "\nHost key fingerprint for '%s' is %s.\n", address, fingerprint)
+	if knownHostsFile != "" {
+		fmt.Fprintf(os.Stderr, "Host key verified by '%s'.\n", knownHostsFile)
+		return nil
+	}
+	return confirmPrompt(os.Stderr, force, stdin, "Do you trust this host key?", "🚫 Host key not trusted because response did not match 'yes'")
+}
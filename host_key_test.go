@@ -0,0 +1,153 @@
+package rove
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evantbyrne/trance"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyVerify(t *testing.T) {
+	key := testHostKey(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+
+	machine := &Machine{Name: "default", HostKeyFingerprint: ssh.FingerprintSHA256(key)}
+	if err := hostKeyVerify(machine)("192.0.2.1:22", remote, key); err != nil {
+		t.Errorf("expected matching host key to verify: %v", err)
+	}
+
+	machine.HostKeyFingerprint = ssh.FingerprintSHA256(testHostKey(t))
+	if err := hostKeyVerify(machine)("192.0.2.1:22", remote, key); err == nil {
+		t.Error("expected changed host key to be rejected.")
+	}
+
+	machine.HostKeyFingerprint = ""
+	if err := hostKeyVerify(machine)("192.0.2.1:22", remote, key); err == nil {
+		t.Error("expected unrecorded host key to be rejected.")
+	}
+}
+
+func TestHostKeyTrust(t *testing.T) {
+	key := testHostKey(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+
+	fingerprint := ""
+	callback, err := hostKeyTrust("", &fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("192.0.2.1:22", remote, key); err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint != ssh.FingerprintSHA256(key) {
+		t.Errorf("'%s' did not match expected fingerprint.", fingerprint)
+	}
+
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(file, []byte(knownhosts.Line([]string{"192.0.2.1"}, key)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fingerprint = ""
+	callback, err = hostKeyTrust(file, &fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("192.0.2.1:22", remote, key); err != nil {
+		t.Errorf("expected known host key to be trusted: %v", err)
+	}
+	if fingerprint != ssh.FingerprintSHA256(key) {
+		t.Errorf("'%s' did not match expected fingerprint.", fingerprint)
+	}
+	if err := callback("192.0.2.1:22", remote, testHostKey(t)); err == nil {
+		t.Error("expected host key missing from known_hosts to be rejected.")
+	}
+}
+
+func TestMachineHostKeyFingerprint(t *testing.T) {
+	if err := testDatabase(func() error {
+		err := trance.Query[Machine]().
+			Insert(&Machine{
+				Address:            "192.0.2.1",
+				HostKeyFingerprint: "SHA256:fake",
+				KeyPath:            "id_ed25519",
+				Name:               "fingerprint",
+				Port:               22,
+				User:               "root",
+			}).
+			Error
+		if err != nil {
+			return err
+		}
+		machine, err := trance.Query[Machine]().Filter("name", "=", "fingerprint").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if machine.HostKeyFingerprint != "SHA256:fake" {
+			t.Errorf("'%s' did not match expected fingerprint.", machine.HostKeyFingerprint)
+		}
+		return trance.Query[Machine]().Filter("name", "=", "fingerprint").Delete().Error
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHostKeyFirstUse(t *testing.T) {
+	if err := testDatabase(func() error {
+		if err := trance.Query[Machine]().Insert(&Machine{Address: "192.0.2.1", Name: "legacy", Port: 22, User: "root"}).Error; err != nil {
+			return err
+		}
+		defer trance.Query[Machine]().Delete()
+		machine, err := trance.Query[Machine]().Filter("name", "=", "legacy").CollectFirst()
+		if err != nil {
+			return err
+		}
+
+		err = hostKeyFirstUse(machine, "SHA256:abc", nil, false)
+		if err == nil || !strings.Contains(err.Error(), "`rove machine rekey legacy`") {
+			t.Errorf("expected error naming rekey command, got %v", err)
+		}
+
+		capture(t).Run(func() error {
+			if err := hostKeyFirstUse(machine, "SHA256:abc", strings.NewReader("no\n"), true); err == nil {
+				t.Error("expected denied host key to be rejected.")
+			}
+			return nil
+		})
+		if machine.HostKeyFingerprint != "" {
+			t.Errorf("denied host key '%s' was recorded.", machine.HostKeyFingerprint)
+		}
+
+		capture(t).Run(func() error {
+			return hostKeyFirstUse(machine, "SHA256:abc", strings.NewReader("yes\n"), true)
+		}).ExpectStdout("")
+		saved, err := trance.Query[Machine]().Filter("name", "=", "legacy").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if machine.HostKeyFingerprint != "SHA256:abc" || saved.HostKeyFingerprint != "SHA256:abc" {
+			t.Errorf("host key was not recorded: '%s' '%s'", machine.HostKeyFingerprint, saved.HostKeyFingerprint)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,7 +14,7 @@ import (
 type InspectCommand struct {
 	Name string `arg:"" name:"name" help:"Name of service or task."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 	Json       bool   `flag:"" name:"json"`
@@ -139,15 +140,17 @@ func (cmd *InspectCommand) Do(conn SshRunner, stdin io.Reader) error {
 		}).
 		OnError(func(err error) error {
 			if err != nil {
-				fmt.Println("🚫 Could not inspect service")
+				if !cmd.Json {
+					fmt.Println("🚫 Could not inspect service")
+				}
 			}
 			return err
 		}).
 		Error()
 }
 
-func (cmd *InspectCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
-	})
+func (cmd *InspectCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	}))
 }
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,7 +14,7 @@ type LoginCommand struct {
 	Username     string   `arg:"" name:"username" help:"Docker registery username."`
 	PasswordFile *os.File `arg:"" name:"password-file" help:"Password/token file. Use dash (-) to read from STDIN."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 	Registry   string `flag:"" name:"registry" help:"Docker registry server."`
@@ -70,8 +71,8 @@ func (cmd *LoginCommand) Do(conn SshRunner, stdin io.Reader) error {
 	return err
 }
 
-func (cmd *LoginCommand) Run() error {
+func (cmd *LoginCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
 	})
 }
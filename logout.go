@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -8,7 +9,7 @@ import (
 )
 
 type LogoutCommand struct {
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 	Registry   string `flag:"" name:"registry" help:"Docker registry server."`
@@ -36,8 +37,8 @@ func (cmd *LogoutCommand) Do(conn SshRunner, stdin io.Reader) error {
 		Error()
 }
 
-func (cmd *LogoutCommand) Run() error {
+func (cmd *LogoutCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
 	})
 }
@@ -1,8 +1,10 @@
 package rove
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/alessio/shellescape"
 )
@@ -10,7 +12,7 @@ import (
 type LogsCommand struct {
 	Name string `arg:"" name:"name" help:"Name of service."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Follow     bool   `flag:"" name:"follow" short:"f" help:"Follow log output."`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
@@ -19,7 +21,7 @@ type LogsCommand struct {
 	Timestamps bool   `flag:"" name:"timestamps" short:"t" help:"Show timestamps."`
 }
 
-func (cmd *LogsCommand) Run() error {
+func (cmd *LogsCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
 		command := ShellCommand{
 			Name: "docker service logs",
@@ -60,10 +62,16 @@ func (cmd *LogsCommand) Run() error {
 		if cmd.Follow && cmd.Timeout != "" {
 			command.Name = fmt.Sprintf("timeout --verbose %s %s", cmd.Timeout, command.Name)
 		}
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
-			return conn.Run(command.String(), func(res string) error {
-				fmt.Print(res)
-				return nil
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+			return conn.Stream(command.String(), StreamHandler{
+				Stderr: func(line string) error {
+					fmt.Fprintln(os.Stderr, line)
+					return nil
+				},
+				Stdout: func(line string) error {
+					fmt.Println(line)
+					return nil
+				},
 			}).Error()
 		})
 	})
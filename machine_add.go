@@ -1,87 +1,190 @@
 package rove
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"strings"
+	"time"
 
 	"github.com/evantbyrne/trance"
 )
 
 type DockerInfoJson struct {
-	Swarm DockerInfoSwarmJson `json:"Swarm"`
+	ServerVersion string              `json:"ServerVersion"`
+	Swarm         DockerInfoSwarmJson `json:"Swarm"`
 }
 
 type DockerInfoSwarmJson struct {
-	NodeID         string `json:"NodeID"`
-	LocalNodeState string `json:"LocalNodeState"`
+	ControlAvailable bool   `json:"ControlAvailable"`
+	Error            string `json:"Error"`
+	NodeAddr         string `json:"NodeAddr"`
+	NodeID           string `json:"NodeID"`
+	LocalNodeState   string `json:"LocalNodeState"`
 }
 
 type MachineAddCommand struct {
 	Name           string `arg:"" name:"name" help:"Name of remote machine."`
-	Address        string `arg:"" name:"address" help:"Public address of remote machine."`
-	User           string `arg:"" name:"user" help:"User of remote machine."`
-	PrivateKeyFile string `arg:"" name:"pk" help:"Private key file." type:"path"`
-
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
-	Port       int64  `flag:"" name:"port" help:"SSH port of remote machine." default:"22"`
-	Skip       bool   `flag:"" name:"skip" help:"Skip installation steps on remote machine."`
+	Address        string `arg:"" name:"address" optional:"" help:"Public address of remote machine. Optional with --ssh-host."`
+	User           string `arg:"" name:"user" optional:"" help:"User of remote machine. Optional with --ssh-host."`
+	PrivateKeyFile string `arg:"" name:"pk" optional:"" help:"Private key file. Omit to authenticate with the SSH agent." type:"path"`
+
+	AutoUpdates    bool          `flag:"" name:"auto-updates" help:"Install and enable automatic OS security updates."`
+	CommandTimeout time.Duration `flag:"" name:"machine-command-timeout" help:"Timeout for each remote command on this machine. Overrides --command-timeout on every command."`
+	ConfigFile     string        `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	ConnectTimeout time.Duration `flag:"" name:"machine-connect-timeout" help:"Timeout for establishing SSH connections to this machine. Overrides --connect-timeout on every command."`
+	DockerVersion  string        `flag:"" name:"docker-version" help:"Docker engine version to install, such as 27.3.1. Defaults to the latest release."`
+	Force          bool          `flag:"" name:"force" help:"Skip confirmations."`
+	Join           string        `flag:"" name:"join" help:"Join the swarm of an existing manager machine instead of creating a new swarm."`
+	Jump           string        `flag:"" name:"jump" help:"Connect through jump host. Either the name of a configured machine or user@host:port, comma-separated to chain several."`
+	Keepalive      time.Duration `flag:"" name:"machine-keepalive" help:"Interval between SSH keepalives for this machine. Overrides --keepalive on every command."`
+	KnownHosts     string        `flag:"" name:"known-hosts" help:"Verify host key against known_hosts file instead of prompting." type:"path"`
+	Port           int64         `flag:"" name:"port" help:"SSH port of remote machine." default:"22"`
+	Role           string        `flag:"" name:"role" help:"Swarm role when joining with --join. Either worker or manager." enum:"worker,manager" default:"worker"`
+	Skip           bool          `flag:"" name:"skip" help:"Skip installation steps on remote machine."`
+	SshHost        string        `flag:"" name:"ssh-host" help:"Host alias from ~/.ssh/config. Its HostName, User, Port, IdentityFile and ProxyJump take precedence."`
 }
 
-func (cmd *MachineAddCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		exists, err := trance.Query[Machine]().Filter("name", "=", cmd.Name).Exists()
-		if err != nil {
-			return fmt.Errorf("unable to check if machine exists: %v", err)
-		}
-		if exists {
+func (cmd *MachineAddCommand) Run(ctx context.Context) error {
+	return DatabaseCreate(cmd.ConfigFile, func() error {
+		// Exists leaves its rows open when a machine is found, so the name is checked with CollectFirst.
+		_, err := trance.Query[Machine]().Filter("name", "=", cmd.Name).CollectFirst()
+		if err == nil {
 			return fmt.Errorf("machine with name '%s' already configured", cmd.Name)
 		}
+		if !errors.Is(err, trance.ErrorNotFound{}) {
+			return fmt.Errorf("unable to check if machine exists: %v", err)
+		}
 
-		key, err := os.ReadFile(cmd.PrivateKeyFile)
+		if err := dockerVersionValidate(cmd.DockerVersion); err != nil {
+			return err
+		}
+		if cmd.SshHost == "" && (cmd.Address == "" || cmd.User == "") {
+			return fmt.Errorf("🚫 Machine requires an address and user, or an SSH config alias passed with --ssh-host")
+		}
+		machine, err := sshConfigResolve(&Machine{
+			Address:        cmd.Address,
+			CommandTimeout: int64(cmd.CommandTimeout.Seconds()),
+			ConnectTimeout: int64(cmd.ConnectTimeout.Seconds()),
+			Keepalive:      int64(cmd.Keepalive.Seconds()),
+			KeyPath:        cmd.PrivateKeyFile,
+			Port:           cmd.Port,
+			ProxyJump:      cmd.Jump,
+			SshConfigHost:  cmd.SshHost,
+			User:           cmd.User,
+		})
 		if err != nil {
-			return fmt.Errorf("unable to read private key file: %v", err)
+			return err
+		}
+
+		if cmd.AutoUpdates && cmd.Skip {
+			return fmt.Errorf("🚫 The `--auto-updates` and `--skip` flags cannot be combined")
 		}
-		err = SshConnect(fmt.Sprintf("%s:%d", cmd.Address, cmd.Port), cmd.User, key, func(conn SshRunner, stdin io.Reader) error {
-			fmt.Printf("\nConnected to remote address '%s@%s:%d'.\n", cmd.User, cmd.Address, cmd.Port)
 
+		var join *swarmJoin
+		if cmd.Join != "" {
 			if cmd.Skip {
-				fmt.Println("Skipping install steps.")
-				return nil
+				return fmt.Errorf("🚫 The `--join` and `--skip` flags cannot be combined")
+			}
+			join, err = swarmJoinFetch(ctx, cmd.Join, cmd.Role)
+			if err != nil {
+				fmt.Printf("🚫 Could not fetch join token from machine '%s'\n", cmd.Join)
+				return err
+			}
+		}
+
+		auth, closeAuth, err := sshAuth(machine.KeyPath)
+		if err != nil {
+			return err
+		}
+		defer closeAuth()
+		jump, closeJump, err := sshJump(ctx, machine.ProxyJump, machine.User, auth, 0)
+		if err != nil {
+			return err
+		}
+		defer closeJump()
+		fingerprint := ""
+		hostKeyCallback, err := hostKeyTrust(cmd.KnownHosts, &fingerprint)
+		if err != nil {
+			return err
+		}
+		var release OsRelease
+		err = sshConnect(ctx, fmt.Sprintf("%s:%d", machine.Address, machine.Port), machine.User, auth, hostKeyCallback, jump, sshOptions(machine), func(conn SshRunner, stdin io.Reader) error {
+			fmt.Printf("\nConnected to remote address '%s@%s:%d'.\n", machine.User, machine.Address, machine.Port)
+
+			if err := confirmHostKey(machine.Address, fingerprint, cmd.KnownHosts, cmd.Force, stdin); err != nil {
+				return err
 			}
 
-			missingApt := false
-			missingUfw := false
-			mustEnableUfw := true
-			mustInstallDocker := true
-			mustEnableSwarm := true
 			err := conn.
-				Run("command -v apt-get", func(_ string) error {
+				Run("cat /etc/os-release", func(res string) error {
+					release = osReleaseParse(res)
 					return nil
 				}).
 				OnError(func(error) error {
-					missingApt = true
-					return nil
-				}).
-				Run("command -v ufw", func(_ string) error {
 					return nil
 				}).
-				OnError(func(error) error {
-					missingUfw = true
-					mustEnableUfw = false
-					return ErrorSkip{}
-				}).
-				Run("sudo ufw status", func(res string) error {
-					if strings.HasPrefix(res, "Status: active") {
-						mustEnableUfw = false
-					}
-					return nil
-				}).
-				OnError(SkipReset).
+				Error()
+			if err != nil {
+				return err
+			}
+
+			if cmd.Skip {
+				fmt.Println("Skipping install steps.")
+				return nil
+			}
+
+			p, err := provisionerDetect(conn, release)
+			if err != nil {
+				return err
+			}
+			mustInstallFirewall := false
+			mustEnableFirewall := false
+			mustInstallDocker := true
+			mustEnableSwarm := true
+			mustInstallUpdates := false
+			mustEnableUpdates := false
+			if p.Firewall != "" {
+				firewallStatus, firewallActive := p.FirewallStatus()
+				err = conn.
+					Run(p.FirewallCheck(), func(_ string) error {
+						return nil
+					}).
+					OnError(func(error) error {
+						mustInstallFirewall = p.Packages != ""
+						mustEnableFirewall = mustInstallFirewall
+						return ErrorSkip{}
+					}).
+					Run(firewallStatus, func(res string) error {
+						mustEnableFirewall = !firewallActive(res)
+						return nil
+					}).
+					OnError(func(err error) error {
+						if !errors.Is(err, ErrorSkip{}) {
+							mustEnableFirewall = true
+						}
+						return nil
+					}).
+					Error()
+				if err != nil {
+					return err
+				}
+			}
+			if cmd.AutoUpdates {
+				if p.Updates() == "" {
+					return fmt.Errorf("🚫 Automatic updates are not supported on OS '%s'. Rerun this command without --auto-updates", release)
+				}
+				installed, enabled, err := updatesProbe(conn, p)
+				if err != nil {
+					return err
+				}
+				mustInstallUpdates = !installed
+				mustEnableUpdates = !enabled
+			}
+			err = conn.
 				Run("command -v docker", func(_ string) error {
 					mustInstallDocker = false
 					return nil
@@ -96,6 +199,9 @@ func (cmd *MachineAddCommand) Run() error {
 						return err
 					}
 					if dockerInfo.Swarm.NodeID != "" {
+						if join != nil && !join.Nodes[dockerInfo.Swarm.NodeID] {
+							return fmt.Errorf("🚫 Machine is already part of another swarm. Run `docker swarm leave` on it before joining '%s'", cmd.Join)
+						}
 						mustEnableSwarm = false
 					}
 					return nil
@@ -106,22 +212,30 @@ func (cmd *MachineAddCommand) Run() error {
 				return err
 			}
 
-			if missingApt && missingUfw {
-				fmt.Println("\n⚠️  Warning: ufw missing on system without apt. Cannot enable firewall. You should install ufw on the target machine and rerun this command. Alternatively, you may manually disallow access to Docker Swarm management ports.")
+			if p.Firewall == "" {
+				fmt.Printf("\n⚠️  Warning: ufw and firewalld missing on unsupported OS '%s'. Cannot enable firewall. You should install ufw or firewalld on the target machine and rerun this command. Alternatively, you may manually disallow access to Docker Swarm management ports.\n", release)
 			}
 
-			if (!missingApt && missingUfw) || mustEnableUfw || mustInstallDocker || mustEnableSwarm {
+			if mustInstallFirewall || mustEnableFirewall || mustInstallDocker || mustInstallUpdates || mustEnableUpdates || mustEnableSwarm {
 				fmt.Print("\nRove will make the following changes to remote machine:\n\n")
-				if !missingApt && missingUfw {
-					fmt.Println(" ~ Install firewall")
+				if mustInstallFirewall {
+					fmt.Printf(" ~ Install firewall (%s)\n", p.Firewall)
 				}
-				if (!missingApt && missingUfw) || mustEnableUfw {
-					fmt.Println(" ~ Enable firewall")
+				if mustEnableFirewall {
+					fmt.Printf(" ~ Enable firewall (%s)\n", p.Firewall)
 				}
 				if mustInstallDocker {
-					fmt.Println(" ~ Install docker")
+					fmt.Printf(" ~ Install docker %s\n", cmp.Or(cmd.DockerVersion, "(latest)"))
+				}
+				if mustInstallUpdates {
+					fmt.Printf(" ~ Install automatic updates (%s)\n", p.Updates())
 				}
-				if mustEnableSwarm {
+				if mustEnableUpdates {
+					fmt.Printf(" ~ Enable automatic security updates (%s)\n", p.Updates())
+				}
+				if mustEnableSwarm && join != nil {
+					fmt.Printf(" ~ Join swarm of '%s' as %s\n", cmd.Join, cmd.Role)
+				} else if mustEnableSwarm {
 					fmt.Println(" ~ Enable swarm")
 				}
 				if err := confirmDeployment(cmd.Force, stdin); err != nil {
@@ -132,56 +246,69 @@ func (cmd *MachineAddCommand) Run() error {
 				fmt.Println("\nNo changes needed.")
 			}
 
-			if !missingApt && missingUfw {
-				err = conn.
-					Run("sudo apt-get install -y ufw", func(res string) error {
-						fmt.Println("sudo apt-get install -y ufw", res)
-						mustEnableUfw = true
-						return nil
-					}).
-					Error()
-				if err != nil {
+			if mustInstallFirewall {
+				if err := provisionRun(conn, p.FirewallInstall()); err != nil {
 					return err
 				}
 			}
 
-			if mustEnableUfw {
-				err = conn.
-					Run("sudo ufw logging on", func(res string) error {
-						fmt.Println("sudo ufw logging on", res)
-						return nil
-					}).
-					Run(fmt.Sprintf("sudo ufw allow %d/tcp", cmd.Port), func(res string) error {
-						fmt.Println(fmt.Sprintf("sudo ufw allow %d/tcp", cmd.Port), res)
-						return nil
-					}).
-					Run("sudo ufw --force enable", func(res string) error {
-						fmt.Println("sudo ufw enable", res)
-						fmt.Println("~ Enabled firewall")
-						return nil
-					}).
-					Error()
-				if err != nil {
+			if mustEnableFirewall {
+				if err := provisionRun(conn, p.FirewallEnable(machine.Port)); err != nil {
 					return err
 				}
+				fmt.Println("~ Enabled firewall")
 			}
 
 			if mustInstallDocker {
-				// Via: https://docs.docker.com/engine/install/ubuntu/#install-using-the-convenience-script
+				repoSetup, err := p.DockerRepoSetup()
+				if err != nil {
+					return err
+				}
+				install, err := p.DockerInstall(cmd.DockerVersion)
+				if err != nil {
+					return err
+				}
+				if err := provisionRunQuiet(conn, append(repoSetup, install...)); err != nil {
+					return err
+				}
+				fmt.Println("~ Installed docker")
+			}
+
+			if mustInstallUpdates {
+				if err := provisionRunQuiet(conn, p.UpdatesInstall()); err != nil {
+					return err
+				}
+				fmt.Println("~ Installed automatic updates")
+			}
+
+			if mustEnableUpdates {
+				if err := provisionRunQuiet(conn, p.UpdatesEnable()); err != nil {
+					return err
+				}
+				fmt.Println("~ Enabled automatic security updates")
+			}
+
+			if mustEnableSwarm && join != nil {
+				// Machines in the cluster must accept swarm traffic from this machine before it can join.
+				joining := *machine
+				joining.Name = cmd.Name
+				if err := firewallSwarmSync(ctx, join.Cluster, &joining); err != nil {
+					fmt.Println("🚫 Could not update swarm firewall rules")
+					return err
+				}
 				err = conn.
-					Run("curl -fsSL https://get.docker.com | sh", func(_ string) error {
-						fmt.Println("~ Installed docker")
+					Run(join.Command(machine.Address), func(_ string) error {
+						fmt.Printf("~ Joined swarm of '%s' as %s\n", cmd.Join, cmd.Role)
 						return nil
 					}).
+					OnError(join.Redact).
 					Error()
 				if err != nil {
 					return err
 				}
-			}
-
-			if mustEnableSwarm {
+			} else if mustEnableSwarm {
 				err = conn.
-					Run(fmt.Sprintf("docker swarm init --advertise-addr %s", cmd.Address), func(_ string) error {
+					Run(fmt.Sprintf("docker swarm init --advertise-addr %s", machine.Address), func(_ string) error {
 						fmt.Println("~ Enabled swarm")
 						return nil
 					}).
@@ -196,13 +323,26 @@ func (cmd *MachineAddCommand) Run() error {
 			fmt.Println("🚫 Could not add machine")
 			return err
 		}
+		cluster := clusterNew()
+		if join != nil {
+			cluster = join.Cluster
+		}
 		return trance.Query[Machine]().
 			Insert(&Machine{
-				Address: cmd.Address,
-				KeyPath: cmd.PrivateKeyFile,
-				Name:    cmd.Name,
-				Port:    cmd.Port,
-				User:    cmd.User,
+				Address:            cmd.Address,
+				Cluster:            cluster,
+				CommandTimeout:     machine.CommandTimeout,
+				ConnectTimeout:     machine.ConnectTimeout,
+				DockerVersion:      cmd.DockerVersion,
+				HostKeyFingerprint: fingerprint,
+				Keepalive:          machine.Keepalive,
+				KeyPath:            cmd.PrivateKeyFile,
+				Name:               cmd.Name,
+				Os:                 release.String(),
+				Port:               cmd.Port,
+				ProxyJump:          cmd.Jump,
+				SshConfigHost:      cmd.SshHost,
+				User:               cmd.User,
 			}).
 			Then(func(_ sql.Result, _ *Machine) error {
 				return SetPreference(DefaultMachine, cmd.Name).Error
@@ -215,6 +355,16 @@ func (cmd *MachineAddCommand) Run() error {
 				fmt.Println("🚫 Could not add machine")
 				return err
 			}).
+			Then(func(_ sql.Result, _ *Machine) error {
+				if cmd.Skip || join == nil {
+					return nil
+				}
+				if err := firewallSwarmSync(ctx, cluster); err != nil {
+					fmt.Println("🚫 Could not update swarm firewall rules")
+					return err
+				}
+				return nil
+			}).
 			Error
 	})
 }
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -10,15 +11,23 @@ import (
 type MachineDeleteCommand struct {
 	Name string `arg:"" name:"machine" help:"Name of machine."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 }
 
-func (cmd *MachineDeleteCommand) Run() error {
+func (cmd *MachineDeleteCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return trance.Query[Machine]().
+		cluster := ""
+		err := trance.Query[Machine]().
 			Filter("name", "=", cmd.Name).
 			First().
-			Then(func(_ *Machine) error {
+			Then(func(machine *Machine) error {
+				cluster = machine.Cluster
+				if err := trance.Query[MachineGroup]().
+					Filter("machine_id", "=", machine.Id).
+					Delete().
+					Error; err != nil {
+					return err
+				}
 				return trance.Query[Machine]().
 					Filter("name", "=", cmd.Name).
 					Delete().
@@ -42,5 +51,14 @@ func (cmd *MachineDeleteCommand) Run() error {
 				return err
 			}).
 			Error
+		if err != nil {
+			return err
+		}
+		// Remaining machines in the cluster no longer accept swarm traffic from the deleted machine.
+		if err := firewallSwarmSync(ctx, cluster); err != nil {
+			fmt.Println("🚫 Could not update swarm firewall rules on remaining machines")
+			return err
+		}
+		return nil
 	})
 }
@@ -0,0 +1,246 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MachineDoctorCommand struct {
+	Name string `arg:"" name:"name" optional:"" help:"Name of machine. Defaults to the current machine."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
+
+	dockerVersion string
+}
+
+type MachineDoctorJson struct {
+	Checks []DoctorCheckJson `json:"checks"`
+	Status string            `json:"status"`
+}
+
+type DoctorCheckJson struct {
+	Message string `json:"message"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+}
+
+const (
+	DoctorPass = "pass"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+)
+
+// Thresholds at which doctor checks warn or fail.
+const (
+	doctorClockSkewWarn = 5 * time.Second
+	doctorClockSkewFail = time.Minute
+	doctorDiskWarn      = 80
+	doctorDiskFail      = 90
+)
+
+func (cmd *MachineDoctorCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	output := MachineDoctorJson{
+		Checks: make([]DoctorCheckJson, 0),
+	}
+	check := func(name string, status string, message string, args ...any) {
+		output.Checks = append(output.Checks, DoctorCheckJson{
+			Message: fmt.Sprintf(message, args...),
+			Name:    name,
+			Status:  status,
+		})
+	}
+	// probe runs a read-only command, turning its failure into a failed check instead of ending the command.
+	probe := func(name string, command string, callback func(string) error) bool {
+		ok := true
+		err := conn.
+			Run(command, callback).
+			OnError(func(err error) error {
+				ok = false
+				check(name, DoctorFail, "`%s` failed: %s", command, strings.SplitN(err.Error(), "\n", 2)[0])
+				return nil
+			}).
+			Error()
+		return err == nil && ok
+	}
+
+	var release OsRelease
+	probe("os", "cat /etc/os-release", func(res string) error {
+		release = osReleaseParse(res)
+		return nil
+	})
+	p, err := provisionerDetect(conn, release)
+	if err != nil {
+		return err
+	}
+	if p.Firewall == "" {
+		check("firewall", DoctorWarn, "ufw and firewalld missing on OS '%s'", release)
+	} else {
+		firewallStatus, firewallActive := p.FirewallStatus()
+		probe("firewall", firewallStatus, func(res string) error {
+			if firewallActive(res) {
+				check("firewall", DoctorPass, "%s is active", p.Firewall)
+			} else {
+				check("firewall", DoctorFail, "%s is not active", p.Firewall)
+			}
+			return nil
+		})
+	}
+
+	probe("disk", "df -P /", func(res string) error {
+		status, message := doctorDisk(res)
+		check("disk", status, "%s", message)
+		return nil
+	})
+
+	before := time.Now()
+	probe("clock", "date +%s", func(res string) error {
+		status, message := doctorClock(res, before, time.Now())
+		check("clock", status, "%s", message)
+		return nil
+	})
+
+	var dockerInfo DockerInfoJson
+	dockerRunning := probe("docker", "docker info --format json", func(res string) error {
+		if err := json.Unmarshal([]byte(res), &dockerInfo); err != nil {
+			return fmt.Errorf("could not parse docker info JSON: %w", err)
+		}
+		status, message := doctorDocker(dockerInfo, cmd.dockerVersion)
+		check("docker", status, "%s", message)
+		return nil
+	})
+	if dockerRunning {
+		status, message := doctorSwarm(dockerInfo.Swarm)
+		if status == DoctorPass && dockerInfo.Swarm.ControlAvailable {
+			// Managers that have lost quorum still report an active node state, but cannot list nodes.
+			probe("swarm", "docker node ls --quiet", func(_ string) error {
+				check("swarm", status, "%s", message)
+				return nil
+			})
+		} else {
+			check("swarm", status, "%s", message)
+		}
+
+		probe("images", "docker images --filter dangling=true --quiet", func(res string) error {
+			if count := len(strings.Fields(res)); count > 0 {
+				check("images", DoctorWarn, "%d dangling images. Run `docker image prune` to reclaim disk space", count)
+			} else {
+				check("images", DoctorPass, "No dangling images")
+			}
+			return nil
+		})
+	}
+
+	output.Status = DoctorPass
+	for _, c := range output.Checks {
+		if c.Status == DoctorFail {
+			output.Status = DoctorFail
+			break
+		} else if c.Status == DoctorWarn {
+			output.Status = DoctorWarn
+		}
+	}
+
+	if cmd.Json {
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+	} else {
+		for _, c := range output.Checks {
+			switch c.Status {
+			case DoctorPass:
+				fmt.Fprintf(stdout, "✅ %s: %s\n", c.Name, c.Message)
+			case DoctorWarn:
+				fmt.Fprintf(stdout, "⚠️  %s: %s\n", c.Name, c.Message)
+			default:
+				fmt.Fprintf(stdout, "🚫 %s: %s\n", c.Name, c.Message)
+			}
+		}
+	}
+	if output.Status == DoctorFail {
+		return ErrorExit{Code: 1}
+	}
+	return nil
+}
+
+func (cmd *MachineDoctorCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		if machine, err := machineByName(cmd.Name); err == nil {
+			cmd.dockerVersion = machine.DockerVersion
+		}
+		return SshMachineByNameContext(ctx, false, cmd.Name, cmd.Do)
+	}))
+}
+
+// doctorClock compares the remote time in seconds against the midpoint of when the command was sent and answered.
+func doctorClock(res string, before time.Time, after time.Time) (string, string) {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(res), 10, 64)
+	if err != nil {
+		return DoctorFail, fmt.Sprintf("Could not parse remote time '%s'", strings.TrimSpace(res))
+	}
+	local := before.Add(after.Sub(before) / 2)
+	skew := time.Unix(seconds, 0).Sub(local).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	switch {
+	case skew >= doctorClockSkewFail:
+		return DoctorFail, fmt.Sprintf("Clock is off by %s. Swarm certificates and logs depend on accurate time", skew)
+	case skew >= doctorClockSkewWarn:
+		return DoctorWarn, fmt.Sprintf("Clock is off by %s", skew)
+	}
+	return DoctorPass, fmt.Sprintf("Clock is off by %s", skew)
+}
+
+// doctorDisk reads the usage of the root filesystem from `df -P /`.
+func doctorDisk(res string) (string, string) {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(res, "\r\n", "\n")), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 {
+		return DoctorFail, "Could not parse disk usage"
+	}
+	used, err := strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+	if err != nil {
+		return DoctorFail, fmt.Sprintf("Could not parse disk usage '%s'", fields[4])
+	}
+	message := fmt.Sprintf("%d%% of %s used", used, fields[5])
+	switch {
+	case used >= doctorDiskFail:
+		return DoctorFail, message
+	case used >= doctorDiskWarn:
+		return DoctorWarn, message
+	}
+	return DoctorPass, message
+}
+
+func doctorDocker(info DockerInfoJson, pinned string) (string, string) {
+	if pinned != "" && info.ServerVersion != pinned {
+		return DoctorWarn, fmt.Sprintf("Docker %s does not match pinned version %s. Run `rove machine upgrade-docker` to reinstall it", info.ServerVersion, pinned)
+	}
+	return DoctorPass, fmt.Sprintf("Docker %s is running", info.ServerVersion)
+}
+
+func doctorSwarm(swarm DockerInfoSwarmJson) (string, string) {
+	if swarm.Error != "" {
+		return DoctorFail, fmt.Sprintf("Swarm error: %s", swarm.Error)
+	}
+	switch swarm.LocalNodeState {
+	case "active":
+		if swarm.ControlAvailable {
+			return DoctorPass, "Swarm is active on manager node"
+		}
+		return DoctorPass, "Swarm is active on worker node"
+	case "inactive", "":
+		return DoctorFail, "Swarm is not enabled. Run `rove machine add` again to enable it"
+	}
+	return DoctorFail, fmt.Sprintf("Swarm node is %s. The swarm manager may be unreachable", swarm.LocalNodeState)
+}
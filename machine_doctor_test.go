@@ -0,0 +1,102 @@
+package rove
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoctorClock(t *testing.T) {
+	before := time.Unix(1700000000, 0)
+	after := before.Add(2 * time.Second)
+	tests := []struct {
+		res     string
+		status  string
+		message string
+	}{
+		{"1700000001\n", DoctorPass, "Clock is off by 0s"},
+		{"1700000011\n", DoctorWarn, "Clock is off by 10s"},
+		{"1699999800\n", DoctorFail, "Clock is off by 3m21s. Swarm certificates and logs depend on accurate time"},
+		{"Thu Oct 17\n", DoctorFail, "Could not parse remote time 'Thu Oct 17'"},
+	}
+	for _, test := range tests {
+		status, message := doctorClock(test.res, before, after)
+		if status != test.status || message != test.message {
+			t.Errorf("'%s' '%s' did not match expected '%s' '%s'.", status, message, test.status, test.message)
+		}
+	}
+}
+
+func TestDoctorDisk(t *testing.T) {
+	tests := []struct {
+		res     string
+		status  string
+		message string
+	}{
+		{"Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/vda1         25215872 9895400  15303088      40% /\n", DoctorPass, "40% of / used"},
+		{"Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/vda1         25215872 21000000  4215872      84% /\n", DoctorWarn, "84% of / used"},
+		{"Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/vda1         25215872 24000000  1215872      95% /\n", DoctorFail, "95% of / used"},
+		{"", DoctorFail, "Could not parse disk usage"},
+	}
+	for _, test := range tests {
+		status, message := doctorDisk(test.res)
+		if status != test.status || message != test.message {
+			t.Errorf("'%s' '%s' did not match expected '%s' '%s'.", status, message, test.status, test.message)
+		}
+	}
+}
+
+func TestDoctorDocker(t *testing.T) {
+	if status, message := doctorDocker(DockerInfoJson{ServerVersion: "27.3.1"}, ""); status != DoctorPass || message != "Docker 27.3.1 is running" {
+		t.Errorf("'%s' '%s' did not match expected.", status, message)
+	}
+	if status, _ := doctorDocker(DockerInfoJson{ServerVersion: "27.3.1"}, "27.3.1"); status != DoctorPass {
+		t.Errorf("'%s' did not match expected.", status)
+	}
+	if status, _ := doctorDocker(DockerInfoJson{ServerVersion: "27.4.0"}, "27.3.1"); status != DoctorWarn {
+		t.Errorf("'%s' did not match expected.", status)
+	}
+}
+
+func TestDoctorSwarm(t *testing.T) {
+	tests := []struct {
+		swarm  DockerInfoSwarmJson
+		status string
+	}{
+		{DockerInfoSwarmJson{LocalNodeState: "active", ControlAvailable: true}, DoctorPass},
+		{DockerInfoSwarmJson{LocalNodeState: "active"}, DoctorPass},
+		{DockerInfoSwarmJson{LocalNodeState: "inactive"}, DoctorFail},
+		{DockerInfoSwarmJson{LocalNodeState: "pending"}, DoctorFail},
+		{DockerInfoSwarmJson{LocalNodeState: "active", Error: "rpc error: code = Unavailable"}, DoctorFail},
+	}
+	for _, test := range tests {
+		if status, message := doctorSwarm(test.swarm); status != test.status {
+			t.Errorf("'%s' '%s' did not match expected '%s'.", status, message, test.status)
+		}
+	}
+}
+
+func TestMachineDoctorDo(t *testing.T) {
+	mock := &SshConnectionMock{
+		Errors: map[string]error{
+			"cat /etc/os-release":       errors.New("No such file or directory"),
+			"command -v ufw":            errors.New("not found"),
+			"command -v firewall-cmd":   errors.New("not found"),
+			"docker info --format json": errors.New("Cannot connect to the Docker daemon"),
+		},
+		Result: "Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/vda1 100 50 50 50% /\n",
+	}
+	cmd := &MachineDoctorCommand{}
+	capture(t).
+		Run(func() error {
+			if err := cmd.Do(mock, nil); err != (ErrorExit{Code: 1}) {
+				return errors.New("expected exit code 1")
+			}
+			return nil
+		}).
+		ExpectStdout("🚫 os: `cat /etc/os-release` failed: No such file or directory\n" +
+			"⚠️  firewall: ufw and firewalld missing on OS ''\n" +
+			"✅ disk: 50% of / used\n" +
+			"🚫 clock: Could not parse remote time 'Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/vda1 100 50 50 50% /'\n" +
+			"🚫 docker: `docker info --format json` failed: Cannot connect to the Docker daemon\n")
+}
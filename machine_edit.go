@@ -0,0 +1,151 @@
+package rove
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/evantbyrne/trance"
+)
+
+type MachineEditCommand struct {
+	Name string `arg:"" name:"name" help:"Name of machine."`
+
+	Address    string `flag:"" name:"address" help:"New public address of remote machine. Not allowed for machines added with --ssh-host."`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Key        string `flag:"" name:"key" help:"New private key file." type:"path"`
+	Port       int64  `flag:"" name:"port" help:"New SSH port of remote machine. Not allowed for machines added with --ssh-host."`
+	Rename     string `flag:"" name:"rename" help:"New name of machine."`
+	User       string `flag:"" name:"user" help:"New user of remote machine."`
+
+	connect func(context.Context, *Machine, func(SshRunner, io.Reader) error) error
+}
+
+func (cmd *MachineEditCommand) Do(ctx context.Context) error {
+	if cmd.Address == "" && cmd.Key == "" && cmd.Port == 0 && cmd.Rename == "" && cmd.User == "" {
+		return errors.New("🚫 Nothing to change. Pass at least one of --address, --user, --port, --key or --rename")
+	}
+	machine, err := trance.Query[Machine]().Filter("name", "=", cmd.Name).CollectFirst()
+	if err != nil {
+		if errors.Is(err, trance.ErrorNotFound{}) {
+			return fmt.Errorf("🚫 No machine with name '%s' configured", cmd.Name)
+		}
+		return err
+	}
+	if machine.SshConfigHost != "" && (cmd.Address != "" || cmd.Port != 0) {
+		// The SSH config alias is resolved at every connection and would override the edited values.
+		return fmt.Errorf("🚫 Machine '%s' reads its address and port from SSH config host '%s'. Edit ~/.ssh/config instead of passing --address or --port", cmd.Name, machine.SshConfigHost)
+	}
+	if cmd.Rename != "" && cmd.Rename != cmd.Name {
+		// Exists leaves its rows open when a machine is found, so the name is checked with CollectFirst.
+		_, err := trance.Query[Machine]().Filter("name", "=", cmd.Rename).CollectFirst()
+		if err == nil {
+			return fmt.Errorf("🚫 Machine with name '%s' already configured", cmd.Rename)
+		}
+		if !errors.Is(err, trance.ErrorNotFound{}) {
+			return fmt.Errorf("unable to check if machine exists: %v", err)
+		}
+	}
+
+	edited := *machine
+	edited.Address = cmp.Or(cmd.Address, machine.Address)
+	edited.KeyPath = cmp.Or(cmd.Key, machine.KeyPath)
+	edited.Name = cmp.Or(cmd.Rename, machine.Name)
+	edited.Port = cmp.Or(cmd.Port, machine.Port)
+	edited.User = cmp.Or(cmd.User, machine.User)
+	changes := make(map[string]any)
+	if edited.Address != machine.Address {
+		changes["address"] = edited.Address
+	}
+	if edited.KeyPath != machine.KeyPath {
+		changes["key_path"] = edited.KeyPath
+	}
+	if edited.Name != machine.Name {
+		changes["name"] = edited.Name
+	}
+	if edited.Port != machine.Port {
+		changes["port"] = edited.Port
+	}
+	if edited.User != machine.User {
+		changes["user"] = edited.User
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes needed.")
+		return nil
+	}
+
+	// Changes are only saved once the machine is reachable with them. The recorded host key must still match.
+	if edited.Address != machine.Address || edited.KeyPath != machine.KeyPath || edited.Port != machine.Port || edited.User != machine.User {
+		connect := cmd.connect
+		if connect == nil {
+			connect = SshMachineContext
+		}
+		err := connect(ctx, &edited, func(_ SshRunner, _ io.Reader) error {
+			fmt.Printf("Connected to remote address '%s@%s:%d'.\n", edited.User, edited.Address, edited.Port)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("🚫 Could not connect to machine '%s' with new settings. No changes were saved. If the host key changed, run `rove machine rekey %s` after editing the address\n", cmd.Name, cmd.Name)
+			return err
+		}
+		if edited.HostKeyFingerprint != machine.HostKeyFingerprint {
+			// A host key trusted on first use is recorded on the edited machine, which is not saved yet.
+			changes["host_key_fingerprint"] = edited.HostKeyFingerprint
+		}
+	}
+
+	if err := trance.Query[Machine]().Filter("id", "=", machine.Id).UpdateMap(changes).Error; err != nil {
+		fmt.Printf("🚫 Could not update machine '%s'\n", cmd.Name)
+		return err
+	}
+	if edited.Name != machine.Name {
+		if err := trance.Query[Preference]().
+			Filter("name", "=", string(DefaultMachine)).
+			Filter("value", "=", machine.Name).
+			UpdateMap(map[string]any{"value": edited.Name}).
+			Error; err != nil {
+			fmt.Println("🚫 Could not update default machine")
+			return err
+		}
+		// Other machines may connect through this one, either directly or as one hop of a chain.
+		jumpers, err := trance.Query[Machine]().All().Collect()
+		if err != nil {
+			return err
+		}
+		for _, jumper := range jumpers {
+			jump := sshJumpRename(jumper.ProxyJump, machine.Name, edited.Name)
+			if jump == jumper.ProxyJump {
+				continue
+			}
+			if err := trance.Query[Machine]().
+				Filter("id", "=", jumper.Id).
+				UpdateMap(map[string]any{"proxy_jump": jump}).
+				Error; err != nil {
+				fmt.Println("🚫 Could not update machines that jump through this machine")
+				return err
+			}
+		}
+	}
+	fmt.Printf("✅ Updated machine '%s'\n", edited.Name)
+
+	if edited.Address != machine.Address && machine.Cluster != "" {
+		peers, err := trance.Query[Machine]().Filter("cluster", "=", machine.Cluster).All().Collect()
+		if err != nil || len(peers) < 2 {
+			return err
+		}
+		// Other machines in the cluster must accept swarm traffic from the new address.
+		if err := firewallSwarmSync(ctx, machine.Cluster); err != nil {
+			fmt.Println("🚫 Could not update swarm firewall rules")
+			return err
+		}
+	}
+	return nil
+}
+
+func (cmd *MachineEditCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return cmd.Do(ctx)
+	})
+}
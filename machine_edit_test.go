@@ -0,0 +1,115 @@
+package rove
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/evantbyrne/trance"
+)
+
+func TestMachineEdit(t *testing.T) {
+	if err := testDatabase(func() error {
+		for _, machine := range []*Machine{
+			{Address: "192.0.2.1", Name: "bastion", Port: 22, User: "root"},
+			{Address: "10.0.0.5", Name: "app", Port: 22, ProxyJump: "bastion", User: "root"},
+			{Address: "10.0.1.5", Name: "db", Port: 22, ProxyJump: "outer@192.0.2.50:22,bastion", User: "root"},
+		} {
+			if err := trance.Query[Machine]().Insert(machine).Error; err != nil {
+				return err
+			}
+		}
+		defer trance.Query[Machine]().Delete()
+		if err := SetPreference(DefaultMachine, "bastion").Error; err != nil {
+			return err
+		}
+		defer trance.Query[Preference]().Delete()
+
+		connected := make([]Machine, 0)
+		connect := func(_ context.Context, machine *Machine, callback func(SshRunner, io.Reader) error) error {
+			connected = append(connected, *machine)
+			if machine.Address == "192.0.2.99" {
+				return errors.New("dial tcp 192.0.2.99:22: i/o timeout")
+			}
+			if machine.HostKeyFingerprint == "" {
+				machine.HostKeyFingerprint = "SHA256:abc"
+			}
+			return callback(&SshConnectionMock{}, nil)
+		}
+
+		capture(t).Run(func() error {
+			return (&MachineEditCommand{Name: "bastion", Rename: "jump", connect: connect}).Do(context.Background())
+		}).ExpectStdout("✅ Updated machine 'jump'\n")
+		if len(connected) != 0 {
+			t.Errorf("rename should not connect, got %v", connected)
+		}
+		if preference := GetPreference(DefaultMachine); preference != "jump" {
+			t.Errorf("default machine '%s' was not renamed.", preference)
+		}
+		app, err := trance.Query[Machine]().Filter("name", "=", "app").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if app.ProxyJump != "jump" {
+			t.Errorf("proxy jump '%s' was not renamed.", app.ProxyJump)
+		}
+		db, err := trance.Query[Machine]().Filter("name", "=", "db").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if db.ProxyJump != "outer@192.0.2.50:22,jump" {
+			t.Errorf("proxy jump chain '%s' was not renamed.", db.ProxyJump)
+		}
+
+		capture(t).Run(func() error {
+			if err := (&MachineEditCommand{Name: "jump", Address: "192.0.2.99", connect: connect}).Do(context.Background()); err == nil {
+				return errors.New("expected unreachable address to fail")
+			}
+			return nil
+		})
+		jump, err := trance.Query[Machine]().Filter("name", "=", "jump").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if jump.Address != "192.0.2.1" {
+			t.Errorf("address '%s' was saved despite failed connection.", jump.Address)
+		}
+
+		capture(t).Run(func() error {
+			return (&MachineEditCommand{Name: "jump", Address: "192.0.2.2", Port: 2222, User: "deploy", connect: connect}).Do(context.Background())
+		}).ExpectStdout("Connected to remote address 'deploy@192.0.2.2:2222'.\n✅ Updated machine 'jump'\n")
+		jump, err = trance.Query[Machine]().Filter("name", "=", "jump").CollectFirst()
+		if err != nil {
+			return err
+		}
+		if jump.Address != "192.0.2.2" || jump.Port != 2222 || jump.User != "deploy" || jump.HostKeyFingerprint != "SHA256:abc" {
+			t.Errorf("'%#v' was not updated.", jump)
+		}
+
+		if err := (&MachineEditCommand{Name: "jump", Rename: "app", connect: connect}).Do(context.Background()); err == nil {
+			t.Error("expected renaming to an existing machine to fail.")
+		}
+		if err := (&MachineEditCommand{Name: "jump", connect: connect}).Do(context.Background()); err == nil {
+			t.Error("expected edit without changes to fail.")
+		}
+
+		if err := trance.Query[Machine]().Insert(&Machine{Address: "web", Name: "web", Port: 22, SshConfigHost: "web", User: "root"}).Error; err != nil {
+			return err
+		}
+		for _, cmd := range []*MachineEditCommand{
+			{Name: "web", Address: "192.0.2.3", connect: connect},
+			{Name: "web", Port: 2222, connect: connect},
+		} {
+			if err := cmd.Do(context.Background()); err == nil {
+				t.Errorf("expected '%#v' to be rejected for SSH config host.", cmd)
+			}
+		}
+		capture(t).Run(func() error {
+			return (&MachineEditCommand{Name: "web", Rename: "www", connect: connect}).Do(context.Background())
+		}).ExpectStdout("✅ Updated machine 'www'\n")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package rove
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Environment variables that describe an ephemeral machine, for CI jobs that have no .rove file. The machine is used whenever ROVE_MACHINE_ADDRESS is set and no other machine is selected with --machine.
+const (
+	MachineEnvAddress = "ROVE_MACHINE_ADDRESS"
+	MachineEnvHostKey = "ROVE_MACHINE_HOST_KEY"
+	MachineEnvKey     = "ROVE_MACHINE_KEY"
+	MachineEnvName    = "ROVE_MACHINE_NAME"
+	MachineEnvPort    = "ROVE_MACHINE_PORT"
+	MachineEnvUser    = "ROVE_MACHINE_USER"
+)
+
+// sshKeyEnvPrefix marks key paths that name an environment variable holding private key material instead of a file.
+const sshKeyEnvPrefix = "env:"
+
+// machineEnvName returns the name of the machine described by environment variables without validating them, or an empty string when ROVE_MACHINE_ADDRESS is not set.
+func machineEnvName() string {
+	if os.Getenv(MachineEnvAddress) == "" {
+		return ""
+	}
+	return cmp.Or(os.Getenv(MachineEnvName), "env")
+}
+
+// machineEnv returns the machine described by environment variables, or nil when ROVE_MACHINE_ADDRESS is not set.
+func machineEnv() (*Machine, error) {
+	name := machineEnvName()
+	if name == "" {
+		return nil, nil
+	}
+	machine := &Machine{
+		Address: os.Getenv(MachineEnvAddress),
+		Name:    name,
+		Port:    22,
+		User:    os.Getenv(MachineEnvUser),
+	}
+	if machine.User == "" {
+		return nil, fmt.Errorf("🚫 %s must be set along with %s", MachineEnvUser, MachineEnvAddress)
+	}
+	if port := os.Getenv(MachineEnvPort); port != "" {
+		var err error
+		machine.Port, err = strconv.ParseInt(port, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("🚫 Invalid %s '%s'", MachineEnvPort, port)
+		}
+	}
+	if os.Getenv(MachineEnvKey) != "" {
+		machine.KeyPath = sshKeyEnvPrefix + MachineEnvKey
+	}
+	fingerprint, err := hostKeyFingerprint(os.Getenv(MachineEnvHostKey))
+	if err != nil {
+		return nil, err
+	}
+	machine.HostKeyFingerprint = fingerprint
+	return machine, nil
+}
+
+// hostKeyFingerprint accepts a SHA256 fingerprint, a public key in authorized_keys format, or a line of `ssh-keyscan` output.
+func hostKeyFingerprint(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("🚫 %s must be set to the SHA256 fingerprint or public key of the machine's host key. Print it with `ssh-keyscan <address> | ssh-keygen -lf -`", MachineEnvHostKey)
+	}
+	if strings.HasPrefix(value, "SHA256:") {
+		return value, nil
+	}
+	if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(value)); err == nil {
+		return ssh.FingerprintSHA256(key), nil
+	}
+	if _, _, key, _, _, err := ssh.ParseKnownHosts([]byte(value)); err == nil {
+		return ssh.FingerprintSHA256(key), nil
+	}
+	return "", fmt.Errorf("🚫 Could not parse %s. Expected a SHA256 fingerprint or public key", MachineEnvHostKey)
+}
+
+// sshKeyRead reads a private key from a file, or from an environment variable for key paths starting with env:. Keys in environment variables may be base64 encoded, since some CI systems cannot mask multiline values.
+func sshKeyRead(keyPath string) ([]byte, error) {
+	name, found := strings.CutPrefix(keyPath, sshKeyEnvPrefix)
+	if !found {
+		return os.ReadFile(keyPath)
+	}
+	key := strings.TrimSpace(os.Getenv(name))
+	if key == "" {
+		return nil, fmt.Errorf("environment variable %s is empty", name)
+	}
+	if strings.HasPrefix(key, "-----") {
+		return []byte(key + "\n"), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.New("expected a PEM or base64 encoded private key")
+	}
+	return decoded, nil
+}
@@ -0,0 +1,175 @@
+package rove
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/evantbyrne/trance"
+)
+
+type MachineGroupAddCommand struct {
+	Group    string   `arg:"" name:"group" help:"Name of group."`
+	Machines []string `arg:"" name:"machines" help:"Names of machines to add to group."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+}
+
+func (cmd *MachineGroupAddCommand) Do() error {
+	for _, name := range cmd.Machines {
+		machine, err := trance.Query[Machine]().Filter("name", "=", name).CollectFirst()
+		if err != nil {
+			if errors.Is(err, trance.ErrorNotFound{}) {
+				return fmt.Errorf("🚫 No machine with name '%s' configured", name)
+			}
+			return err
+		}
+		// Exists leaves its rows open when a membership is found, so it is checked with CollectFirst.
+		_, err = trance.Query[MachineGroup]().
+			Filter("machine_id", "=", machine.Id).
+			Filter("name", "=", cmd.Group).
+			CollectFirst()
+		if err == nil {
+			fmt.Printf("✅ Machine '%s' already in group '%s'\n", name, cmd.Group)
+			continue
+		}
+		if !errors.Is(err, trance.ErrorNotFound{}) {
+			return err
+		}
+		if err := trance.Query[MachineGroup]().Insert(&MachineGroup{MachineId: machine.Id, Name: cmd.Group}).Error; err != nil {
+			fmt.Printf("🚫 Could not add machine '%s' to group '%s'\n", name, cmd.Group)
+			return err
+		}
+		fmt.Printf("✅ Added machine '%s' to group '%s'\n", name, cmd.Group)
+	}
+	return nil
+}
+
+func (cmd *MachineGroupAddCommand) Run() error {
+	return Database(cmd.ConfigFile, cmd.Do)
+}
+
+type MachineGroupListCommand struct {
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
+}
+
+type MachineGroupListJson struct {
+	Groups []MachineGroupJson `json:"groups"`
+}
+
+type MachineGroupJson struct {
+	Machines []string `json:"machines"`
+	Name     string   `json:"name"`
+}
+
+func (cmd *MachineGroupListCommand) Do() error {
+	groups, err := trance.Query[MachineGroup]().Sort("name").All().Collect()
+	if err != nil {
+		return err
+	}
+	machines, err := trance.Query[Machine]().All().Collect()
+	if err != nil {
+		return err
+	}
+	names := make(map[int64]string)
+	for _, machine := range machines {
+		names[machine.Id] = machine.Name
+	}
+
+	output := MachineGroupListJson{
+		Groups: make([]MachineGroupJson, 0),
+	}
+	for _, group := range groups {
+		if len(output.Groups) == 0 || output.Groups[len(output.Groups)-1].Name != group.Name {
+			output.Groups = append(output.Groups, MachineGroupJson{
+				Machines: make([]string, 0),
+				Name:     group.Name,
+			})
+		}
+		last := &output.Groups[len(output.Groups)-1]
+		last.Machines = append(last.Machines, names[group.MachineId])
+	}
+	for _, group := range output.Groups {
+		slices.Sort(group.Machines)
+	}
+
+	if cmd.Json {
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Println("🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, group := range output.Groups {
+			fmt.Println(group.Name, group.Machines)
+		}
+	}
+	return nil
+}
+
+func (cmd *MachineGroupListCommand) Run() error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, cmd.Do))
+}
+
+type MachineGroupRemoveCommand struct {
+	Group    string   `arg:"" name:"group" help:"Name of group."`
+	Machines []string `arg:"" name:"machines" help:"Names of machines to remove from group."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+}
+
+func (cmd *MachineGroupRemoveCommand) Do() error {
+	for _, name := range cmd.Machines {
+		machine, err := trance.Query[Machine]().Filter("name", "=", name).CollectFirst()
+		if err != nil {
+			if errors.Is(err, trance.ErrorNotFound{}) {
+				fmt.Printf("✅ Machine '%s' not found\n", name)
+				continue
+			}
+			return err
+		}
+		if err := trance.Query[MachineGroup]().
+			Filter("machine_id", "=", machine.Id).
+			Filter("name", "=", cmd.Group).
+			Delete().
+			Error; err != nil {
+			fmt.Printf("🚫 Could not remove machine '%s' from group '%s'\n", name, cmd.Group)
+			return err
+		}
+		fmt.Printf("✅ Removed machine '%s' from group '%s'\n", name, cmd.Group)
+	}
+	return nil
+}
+
+func (cmd *MachineGroupRemoveCommand) Run() error {
+	return Database(cmd.ConfigFile, cmd.Do)
+}
+
+// machinesSelect returns the machines in group, or every machine when group is empty, sorted by name.
+func machinesSelect(group string) ([]*Machine, error) {
+	machines, err := trance.Query[Machine]().Sort("name").All().Collect()
+	if err != nil || group == "" {
+		return machines, err
+	}
+	memberships, err := trance.Query[MachineGroup]().Filter("name", "=", group).All().Collect()
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[int64]bool)
+	for _, membership := range memberships {
+		members[membership.MachineId] = true
+	}
+	selected := make([]*Machine, 0, len(memberships))
+	for _, machine := range machines {
+		if members[machine.Id] {
+			selected = append(selected, machine)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("🚫 No machines in group '%s'", group)
+	}
+	return selected, nil
+}
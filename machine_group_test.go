@@ -0,0 +1,77 @@
+package rove
+
+import (
+	"testing"
+
+	"github.com/evantbyrne/trance"
+)
+
+func TestMachinesSelect(t *testing.T) {
+	if err := testDatabase(func() error {
+		for _, name := range []string{"us-east", "eu-west", "staging"} {
+			if err := trance.Query[Machine]().Insert(&Machine{Address: "192.0.2.1", Name: name, Port: 22, User: "root"}).Error; err != nil {
+				return err
+			}
+		}
+		defer trance.Query[Machine]().Delete()
+		defer trance.Query[MachineGroup]().Delete()
+
+		capture(t).Run(func() error {
+			return (&MachineGroupAddCommand{Group: "prod", Machines: []string{"us-east", "eu-west"}}).Do()
+		})
+		machines, err := machinesSelect("prod")
+		if err != nil {
+			return err
+		}
+		if len(machines) != 2 || machines[0].Name != "eu-west" || machines[1].Name != "us-east" {
+			t.Errorf("unexpected machines in group: %v", machines)
+		}
+		if machines, err = machinesSelect(""); err != nil || len(machines) != 3 {
+			t.Errorf("expected all machines, got %v: %v", machines, err)
+		}
+		if _, err := machinesSelect("missing"); err == nil {
+			t.Error("expected empty group to fail.")
+		}
+		if err := (&MachineGroupAddCommand{Group: "prod", Machines: []string{"missing"}}).Do(); err == nil {
+			t.Error("expected adding unknown machine to fail.")
+		}
+
+		capture(t).Run(func() error {
+			return (&MachineGroupAddCommand{Group: "canary", Machines: []string{"staging"}}).Do()
+		})
+		capture(t).Run(func() error {
+			return (&MachineGroupAddCommand{Group: "canary", Machines: []string{"staging", "us-east"}}).Do()
+		}).ExpectStdout("✅ Machine 'staging' already in group 'canary'\n✅ Added machine 'us-east' to group 'canary'\n")
+		if machines, err = machinesSelect("canary"); err != nil || len(machines) != 2 {
+			t.Errorf("expected both machines in group, got %v: %v", machines, err)
+		}
+		capture(t).Run(func() error {
+			return (&MachineGroupRemoveCommand{Group: "canary", Machines: []string{"staging", "us-east"}}).Do()
+		})
+
+		capture(t).Run(func() error {
+			return (&MachineGroupListCommand{Json: true}).Do()
+		}).ExpectStdout(`{
+    "groups": [
+        {
+            "machines": [
+                "eu-west",
+                "us-east"
+            ],
+            "name": "prod"
+        }
+    ]
+}
+`)
+
+		capture(t).Run(func() error {
+			return (&MachineGroupRemoveCommand{Group: "prod", Machines: []string{"us-east"}}).Do()
+		})
+		if machines, err = machinesSelect("prod"); err != nil || len(machines) != 1 || machines[0].Name != "eu-west" {
+			t.Errorf("unexpected machines after removal: %v: %v", machines, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 
@@ -8,7 +9,7 @@ import (
 )
 
 type MachineListCommand struct {
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
 }
 
@@ -17,7 +18,7 @@ type MachineListJson struct {
 }
 
 func (cmd *MachineListCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
 		return trance.Query[Machine]().
 			Sort("name").
 			All().
@@ -34,11 +35,11 @@ func (cmd *MachineListCommand) Run() error {
 					fmt.Println(string(out))
 				} else {
 					for _, machine := range machines {
-						fmt.Println(machine.Name, machine.Address)
+						fmt.Println(machine.Name, cmp.Or(machine.Address, machine.SshConfigHost))
 					}
 				}
 				return nil
 			}).
 			Error
-	})
+	}))
 }
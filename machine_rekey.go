@@ -0,0 +1,68 @@
+package rove
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/evantbyrne/trance"
+)
+
+type MachineRekeyCommand struct {
+	Name string `arg:"" name:"name" help:"Name of machine."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	KnownHosts string `flag:"" name:"known-hosts" help:"Verify host key against known_hosts file instead of prompting." type:"path"`
+}
+
+func (cmd *MachineRekeyCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return trance.Query[Machine]().
+			Filter("name", "=", cmd.Name).
+			First().
+			OnError(func(err error) error {
+				if errors.Is(err, trance.ErrorNotFound{}) {
+					return fmt.Errorf("🚫 No machine with name '%s' configured", cmd.Name)
+				}
+				return err
+			}).
+			Then(func(machine *Machine) error {
+				fingerprint := ""
+				hostKeyCallback, err := hostKeyTrust(cmd.KnownHosts, &fingerprint)
+				if err != nil {
+					return err
+				}
+				err = sshMachineWithHostKey(ctx, machine, hostKeyCallback, func(_ SshRunner, stdin io.Reader) error {
+					fmt.Printf("\nConnected to remote address '%s@%s:%d'.\n", machine.User, machine.Address, machine.Port)
+					if fingerprint == machine.HostKeyFingerprint {
+						fmt.Printf("\nHost key fingerprint for '%s' is unchanged: %s\n\n", machine.Address, fingerprint)
+						return ErrorSkip{}
+					}
+					if machine.HostKeyFingerprint != "" {
+						fmt.Fprintf(os.Stderr, "\nRecorded host key fingerprint is %s.", machine.HostKeyFingerprint)
+					}
+					return confirmHostKey(machine.Address, fingerprint, cmd.KnownHosts, cmd.Force, stdin)
+				})
+				if err != nil {
+					return SkipReset(err)
+				}
+				return trance.Query[Machine]().
+					Filter("name", "=", cmd.Name).
+					UpdateMap(map[string]any{"host_key_fingerprint": fingerprint}).
+					OnError(func(err error) error {
+						fmt.Printf("🚫 Could not update host key for machine '%s'\n", cmd.Name)
+						return err
+					}).
+					Then(func(_ sql.Result, _ *Machine) error {
+						fmt.Printf("\n✅ Updated host key for machine '%s'\n\n", cmd.Name)
+						return nil
+					}).
+					Error
+			}).
+			Error
+	})
+}
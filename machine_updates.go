@@ -0,0 +1,169 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type MachineUpdatesJson struct {
+	Enabled   bool   `json:"enabled"`
+	Installed bool   `json:"installed"`
+	Package   string `json:"package"`
+}
+
+type MachineUpdatesDisableCommand struct {
+	Name string `arg:"" name:"name" optional:"" help:"Name of machine. Defaults to the current machine."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+}
+
+func (cmd *MachineUpdatesDisableCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return updatesApply(conn, stdin, cmd.Force, false)
+}
+
+func (cmd *MachineUpdatesDisableCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, false, cmd.Name, cmd.Do)
+	})
+}
+
+type MachineUpdatesEnableCommand struct {
+	Name string `arg:"" name:"name" optional:"" help:"Name of machine. Defaults to the current machine."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+}
+
+func (cmd *MachineUpdatesEnableCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return updatesApply(conn, stdin, cmd.Force, true)
+}
+
+func (cmd *MachineUpdatesEnableCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, false, cmd.Name, cmd.Do)
+	})
+}
+
+type MachineUpdatesStatusCommand struct {
+	Name string `arg:"" name:"name" optional:"" help:"Name of machine. Defaults to the current machine."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
+}
+
+func (cmd *MachineUpdatesStatusCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	p, err := updatesProvisioner(conn)
+	if err != nil {
+		return err
+	}
+	installed, enabled, err := updatesProbe(conn, p)
+	if err != nil {
+		return err
+	}
+	output := MachineUpdatesJson{
+		Enabled:   enabled,
+		Installed: installed,
+		Package:   p.Updates(),
+	}
+	if cmd.Json {
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+	} else if enabled {
+		fmt.Fprintf(stdout, "✅ Automatic security updates enabled (%s)\n", output.Package)
+	} else if installed {
+		fmt.Fprintf(stdout, "Automatic security updates disabled (%s)\n", output.Package)
+	} else {
+		fmt.Fprintf(stdout, "Automatic security updates disabled (%s not installed)\n", output.Package)
+	}
+	return nil
+}
+
+func (cmd *MachineUpdatesStatusCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, false, cmd.Name, cmd.Do)
+	}))
+}
+
+// updatesProvisioner detects how to manage automatic updates on the machine, failing on unsupported machines.
+func updatesProvisioner(conn SshRunner) (*provisioner, error) {
+	var release OsRelease
+	err := conn.
+		Run("cat /etc/os-release", func(res string) error {
+			release = osReleaseParse(res)
+			return nil
+		}).
+		OnError(func(error) error {
+			return nil
+		}).
+		Error()
+	if err != nil {
+		return nil, err
+	}
+	p, err := provisionerDetect(conn, release)
+	if err != nil {
+		return nil, err
+	}
+	if p.Updates() == "" {
+		return nil, fmt.Errorf("🚫 Automatic updates are not supported on OS '%s'. Supported systems are Debian and Ubuntu with unattended-upgrades, and Fedora, RHEL and derivatives with dnf-automatic", release)
+	}
+	return p, nil
+}
+
+// updatesApply enables or disables automatic security updates after confirmation.
+func updatesApply(conn SshRunner, stdin io.Reader, force bool, enable bool) error {
+	stdout := conn.Stdout()
+	p, err := updatesProvisioner(conn)
+	if err != nil {
+		return err
+	}
+	installed, enabled, err := updatesProbe(conn, p)
+	if err != nil {
+		return err
+	}
+	mustInstall := enable && !installed
+	if enabled == enable {
+		fmt.Fprintln(stdout, "\nNo changes needed.")
+		return nil
+	}
+
+	fmt.Fprint(stdout, "\nRove will make the following changes to remote machine:\n\n")
+	if mustInstall {
+		fmt.Fprintf(stdout, " ~ Install automatic updates (%s)\n", p.Updates())
+	}
+	if enable {
+		fmt.Fprintf(stdout, " ~ Enable automatic security updates (%s)\n", p.Updates())
+	} else {
+		fmt.Fprintf(stdout, " ~ Disable automatic security updates (%s)\n", p.Updates())
+	}
+	if err := confirmDeploymentTo(stdout, force, stdin); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout)
+
+	if mustInstall {
+		if err := provisionRunQuiet(conn, p.UpdatesInstall()); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "~ Installed automatic updates")
+	}
+	if enable {
+		if err := provisionRunQuiet(conn, p.UpdatesEnable()); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "~ Enabled automatic security updates")
+	} else {
+		if err := provisionRunQuiet(conn, p.UpdatesDisable()); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "~ Disabled automatic security updates")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package rove
+
+import (
+	"slices"
+	"testing"
+)
+
+const machineUpdatesTestResult = `ID=ubuntu
+APT::Periodic::Unattended-Upgrade "1";
+`
+
+func TestMachineUpdatesStatus(t *testing.T) {
+	mock := &SshConnectionMock{Result: machineUpdatesTestResult}
+	cmd := &MachineUpdatesStatusCommand{Json: true}
+	capture(t).
+		Run(func() error {
+			return cmd.Do(mock, nil)
+		}).
+		ExpectStdout(`{
+    "enabled": true,
+    "installed": true,
+    "package": "unattended-upgrades"
+}
+`)
+}
+
+func TestMachineUpdatesEnable(t *testing.T) {
+	mock := &SshConnectionMock{Result: machineUpdatesTestResult}
+	cmd := &MachineUpdatesEnableCommand{Force: true}
+	capture(t).
+		Run(func() error {
+			return cmd.Do(mock, nil)
+		}).
+		ExpectStdout("\nNo changes needed.\n")
+}
+
+func TestMachineUpdatesDisable(t *testing.T) {
+	mock := &SshConnectionMock{Result: machineUpdatesTestResult}
+	cmd := &MachineUpdatesDisableCommand{Force: true}
+	capture(t).
+		Run(func() error {
+			return cmd.Do(mock, nil)
+		}).
+		ExpectStdout("\nRove will make the following changes to remote machine:\n\n ~ Disable automatic security updates (unattended-upgrades)\n\nConfirmations skipped.\n\n~ Disabled automatic security updates\n")
+	expected := []string{
+		"cat /etc/os-release",
+		"command -v unattended-upgrade",
+		"apt-config dump APT::Periodic::Unattended-Upgrade",
+		`printf 'APT::Periodic::Update-Package-Lists "0";\nAPT::Periodic::Unattended-Upgrade "0";\n' | sudo tee /etc/apt/apt.conf.d/20auto-upgrades > /dev/null`,
+	}
+	if !slices.Equal(mock.CommandsRun, expected) {
+		t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+	}
+}
+
+func TestMachineUpdatesUnsupported(t *testing.T) {
+	mock := &SshConnectionMock{Result: "ID=alpine\n"}
+	cmd := &MachineUpdatesEnableCommand{Force: true}
+	if err := cmd.Do(mock, nil); err == nil {
+		t.Error("Expected unsupported OS to fail.")
+	}
+}
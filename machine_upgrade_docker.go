@@ -0,0 +1,119 @@
+package rove
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/evantbyrne/trance"
+)
+
+type MachineUpgradeDockerCommand struct {
+	Name string `arg:"" name:"name" help:"Name of machine."`
+
+	ConfigFile    string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	DockerVersion string `flag:"" name:"docker-version" help:"Docker engine version to install, such as 27.3.1. Defaults to the latest release, which removes any pinned version."`
+	Force         bool   `flag:"" name:"force" help:"Skip confirmations."`
+}
+
+func (cmd *MachineUpgradeDockerCommand) Run(ctx context.Context) error {
+	if err := dockerVersionValidate(cmd.DockerVersion); err != nil {
+		return err
+	}
+	return Database(cmd.ConfigFile, func() error {
+		return trance.Query[Machine]().
+			Filter("name", "=", cmd.Name).
+			First().
+			OnError(func(err error) error {
+				if errors.Is(err, trance.ErrorNotFound{}) {
+					return fmt.Errorf("🚫 No machine with name '%s' configured", cmd.Name)
+				}
+				return err
+			}).
+			Then(func(machine *Machine) error {
+				err := SshMachineContext(ctx, machine, func(conn SshRunner, stdin io.Reader) error {
+					fmt.Printf("\nConnected to remote address '%s@%s:%d'.\n", machine.User, machine.Address, machine.Port)
+					return dockerUpgrade(conn, stdin, cmd.Force, cmd.DockerVersion)
+				})
+				if err != nil {
+					return SkipReset(err)
+				}
+				return trance.Query[Machine]().
+					Filter("name", "=", cmd.Name).
+					UpdateMap(map[string]any{"docker_version": cmd.DockerVersion}).
+					OnError(func(err error) error {
+						fmt.Printf("🚫 Could not update Docker version of machine '%s'\n", cmd.Name)
+						return err
+					}).
+					Then(func(_ sql.Result, _ *Machine) error {
+						fmt.Printf("\n✅ Upgraded Docker on machine '%s'\n\n", cmd.Name)
+						return nil
+					}).
+					Error
+			}).
+			Error
+	})
+}
+
+// dockerUpgrade shows the planned Docker upgrade and installs it after confirmation. It returns ErrorSkip when the machine already runs the pinned version.
+func dockerUpgrade(conn SshRunner, stdin io.Reader, force bool, version string) error {
+	current := ""
+	var release OsRelease
+	err := conn.
+		Run("docker version --format '{{.Server.Version}}'", func(res string) error {
+			current = strings.TrimSpace(res)
+			return nil
+		}).
+		OnError(func(err error) error {
+			fmt.Println("🚫 Could not read Docker version. Install Docker with `rove machine add` first")
+			return err
+		}).
+		Error()
+	if err != nil {
+		return err
+	}
+	if version != "" && version == current {
+		fmt.Printf("\nDocker %s already installed.\n\nNo changes needed.\n\n", current)
+		return ErrorSkip{}
+	}
+
+	err = conn.
+		Run("cat /etc/os-release", func(res string) error {
+			release = osReleaseParse(res)
+			return nil
+		}).
+		Error()
+	if err != nil {
+		return err
+	}
+	p, err := provisionerDetect(conn, release)
+	if err != nil {
+		return err
+	}
+	repoSetup, err := p.DockerRepoSetup()
+	if err != nil {
+		return err
+	}
+	install, err := p.DockerInstall(version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("\nRove will make the following changes to remote machine:\n\n")
+	fmt.Printf(" ~ docker %s => %s\n", cmp.Or(current, "(unknown)"), cmp.Or(version, "(latest)"))
+	fmt.Println("\nRestarting Docker stops and restarts every container on the machine.")
+	if err := confirmDeployment(force, stdin); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	if err := provisionRunQuiet(conn, append(append(repoSetup, install...), "sudo systemctl restart docker")); err != nil {
+		return err
+	}
+	fmt.Println("~ Upgraded docker")
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 type MachineUseCommand struct {
 	Name string `arg:"" name:"name" help:"Machine name."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 }
 
 func (cmd *MachineUseCommand) Run() error {
@@ -0,0 +1,23 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0002 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @type:"TEXT NOT NULL DEFAULT ''"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Port               int64  `@:"port"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0002HostKey struct{}
+
+func (m Migration0002HostKey) Up() error {
+	return trance.Query[machine0002](trance.WeaveConfig{Table: "machine"}).TableColumnAdd("host_key_fingerprint").Error
+}
+
+func (m Migration0002HostKey) Down() error {
+	return trance.Query[machine0002](trance.WeaveConfig{Table: "machine"}).TableColumnDrop("host_key_fingerprint").Error
+}
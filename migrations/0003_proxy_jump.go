@@ -0,0 +1,24 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0003 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @type:"TEXT NOT NULL DEFAULT ''"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0003ProxyJump struct{}
+
+func (m Migration0003ProxyJump) Up() error {
+	return trance.Query[machine0003](trance.WeaveConfig{Table: "machine"}).TableColumnAdd("proxy_jump").Error
+}
+
+func (m Migration0003ProxyJump) Down() error {
+	return trance.Query[machine0003](trance.WeaveConfig{Table: "machine"}).TableColumnDrop("proxy_jump").Error
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0004 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @length:"255"`
+	SshConfigHost      string `@:"ssh_config_host" @type:"TEXT NOT NULL DEFAULT ''"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0004SshConfigHost struct{}
+
+func (m Migration0004SshConfigHost) Up() error {
+	return trance.Query[machine0004](trance.WeaveConfig{Table: "machine"}).TableColumnAdd("ssh_config_host").Error
+}
+
+func (m Migration0004SshConfigHost) Down() error {
+	return trance.Query[machine0004](trance.WeaveConfig{Table: "machine"}).TableColumnDrop("ssh_config_host").Error
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0005 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	CommandTimeout     int64  `@:"command_timeout" @type:"INTEGER NOT NULL DEFAULT 0"`
+	ConnectTimeout     int64  `@:"connect_timeout" @type:"INTEGER NOT NULL DEFAULT 0"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	Keepalive          int64  `@:"keepalive" @type:"INTEGER NOT NULL DEFAULT 0"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @length:"255"`
+	SshConfigHost      string `@:"ssh_config_host" @length:"255"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0005SshTimeouts struct{}
+
+func (m Migration0005SshTimeouts) Up() error {
+	for _, column := range []string{"command_timeout", "connect_timeout", "keepalive"} {
+		if err := trance.Query[machine0005](trance.WeaveConfig{Table: "machine"}).TableColumnAdd(column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Migration0005SshTimeouts) Down() error {
+	for _, column := range []string{"command_timeout", "connect_timeout", "keepalive"} {
+		if err := trance.Query[machine0005](trance.WeaveConfig{Table: "machine"}).TableColumnDrop(column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machineGroup0006 struct {
+	Id        int64  `@:"id" @primary:"true"`
+	MachineId int64  `@:"machine_id"`
+	Name      string `@:"name" @length:"255"`
+}
+
+type Migration0006MachineGroups struct{}
+
+func (m Migration0006MachineGroups) Up() error {
+	return trance.Query[machineGroup0006](trance.WeaveConfig{Table: "machinegroup"}).TableCreate().Error
+}
+
+func (m Migration0006MachineGroups) Down() error {
+	return trance.Query[machineGroup0006](trance.WeaveConfig{Table: "machinegroup"}).TableDrop().Error
+}
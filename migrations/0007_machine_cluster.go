@@ -0,0 +1,29 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0007 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	Cluster            string `@:"cluster" @type:"TEXT NOT NULL DEFAULT ''"`
+	CommandTimeout     int64  `@:"command_timeout"`
+	ConnectTimeout     int64  `@:"connect_timeout"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	Keepalive          int64  `@:"keepalive"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @length:"255"`
+	SshConfigHost      string `@:"ssh_config_host" @length:"255"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0007MachineCluster struct{}
+
+func (m Migration0007MachineCluster) Up() error {
+	return trance.Query[machine0007](trance.WeaveConfig{Table: "machine"}).TableColumnAdd("cluster").Error
+}
+
+func (m Migration0007MachineCluster) Down() error {
+	return trance.Query[machine0007](trance.WeaveConfig{Table: "machine"}).TableColumnDrop("cluster").Error
+}
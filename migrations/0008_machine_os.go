@@ -0,0 +1,30 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0008 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	Cluster            string `@:"cluster" @length:"255"`
+	CommandTimeout     int64  `@:"command_timeout"`
+	ConnectTimeout     int64  `@:"connect_timeout"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	Keepalive          int64  `@:"keepalive"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Os                 string `@:"os" @type:"TEXT NOT NULL DEFAULT ''"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @length:"255"`
+	SshConfigHost      string `@:"ssh_config_host" @length:"255"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0008MachineOs struct{}
+
+func (m Migration0008MachineOs) Up() error {
+	return trance.Query[machine0008](trance.WeaveConfig{Table: "machine"}).TableColumnAdd("os").Error
+}
+
+func (m Migration0008MachineOs) Down() error {
+	return trance.Query[machine0008](trance.WeaveConfig{Table: "machine"}).TableColumnDrop("os").Error
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import "github.com/evantbyrne/trance"
+
+type machine0009 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	Cluster            string `@:"cluster" @length:"255"`
+	CommandTimeout     int64  `@:"command_timeout"`
+	ConnectTimeout     int64  `@:"connect_timeout"`
+	DockerVersion      string `@:"docker_version" @type:"TEXT NOT NULL DEFAULT ''"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	Keepalive          int64  `@:"keepalive"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Os                 string `@:"os" @length:"255"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @length:"255"`
+	SshConfigHost      string `@:"ssh_config_host" @length:"255"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0009DockerVersion struct{}
+
+func (m Migration0009DockerVersion) Up() error {
+	return trance.Query[machine0009](trance.WeaveConfig{Table: "machine"}).TableColumnAdd("docker_version").Error
+}
+
+func (m Migration0009DockerVersion) Down() error {
+	return trance.Query[machine0009](trance.WeaveConfig{Table: "machine"}).TableColumnDrop("docker_version").Error
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"github.com/evantbyrne/trance"
+	"github.com/google/uuid"
+)
+
+type machine0010 struct {
+	Id                 int64  `@:"id" @primary:"true"`
+	Address            string `@:"address" @length:"255"`
+	Cluster            string `@:"cluster" @length:"255"`
+	CommandTimeout     int64  `@:"command_timeout"`
+	ConnectTimeout     int64  `@:"connect_timeout"`
+	DockerVersion      string `@:"docker_version" @type:"TEXT NOT NULL DEFAULT ''"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255"`
+	Keepalive          int64  `@:"keepalive"`
+	KeyPath            string `@:"key_path" @length:"1024"`
+	Name               string `@:"name" @length:"255" @unique:"true"`
+	Os                 string `@:"os" @length:"255"`
+	Port               int64  `@:"port"`
+	ProxyJump          string `@:"proxy_jump" @length:"255"`
+	SshConfigHost      string `@:"ssh_config_host" @length:"255"`
+	User               string `@:"user" @length:"255"`
+}
+
+type Migration0010ClusterId struct{}
+
+// Up replaces clusters named after their first manager with random IDs, so that a machine added later under a reused name does not join an old cluster.
+func (m Migration0010ClusterId) Up() error {
+	machines, err := trance.Query[machine0010](trance.WeaveConfig{Table: "machine"}).Filter("cluster", "!=", "").All().Collect()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, machine := range machines {
+		if _, ok := ids[machine.Cluster]; !ok {
+			ids[machine.Cluster] = uuid.NewString()
+		}
+	}
+	for name, id := range ids {
+		if err := trance.Query[machine0010](trance.WeaveConfig{Table: "machine"}).
+			Filter("cluster", "=", name).
+			UpdateMap(map[string]any{"cluster": id}).
+			Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Migration0010ClusterId) Down() error {
+	return nil
+}
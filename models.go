@@ -1,15 +1,30 @@
 package rove
 
 type Machine struct {
-	Id      int64  `@:"id" @primary:"true" json:"-"`
-	Address string `@:"address" @length:"255" json:"address"`
-	KeyPath string `@:"key_path" @length:"1024" json:"-"`
-	Name    string `@:"name" @length:"255" @unique:"true" json:"name"`
-	Port    int64  `@:"port" json:"-"`
-	User    string `@:"user" @length:"255" json:"-"`
+	Id                 int64  `@:"id" @primary:"true" json:"-"`
+	Address            string `@:"address" @length:"255" json:"address"`
+	Cluster            string `@:"cluster" @length:"255" json:"cluster,omitempty"`
+	CommandTimeout     int64  `@:"command_timeout" json:"-"`
+	ConnectTimeout     int64  `@:"connect_timeout" json:"-"`
+	DockerVersion      string `@:"docker_version" @length:"255" json:"docker_version,omitempty"`
+	HostKeyFingerprint string `@:"host_key_fingerprint" @length:"255" json:"-"`
+	Keepalive          int64  `@:"keepalive" json:"-"`
+	KeyPath            string `@:"key_path" @length:"1024" json:"-"`
+	Name               string `@:"name" @length:"255" @unique:"true" json:"name"`
+	Os                 string `@:"os" @length:"255" json:"os,omitempty"`
+	Port               int64  `@:"port" json:"-"`
+	ProxyJump          string `@:"proxy_jump" @length:"255" json:"-"`
+	SshConfigHost      string `@:"ssh_config_host" @length:"255" json:"ssh_config_host,omitempty"`
+	User               string `@:"user" @length:"255" json:"-"`
 }
 
 type Preference struct {
 	Name  string `@:"name" @length:"255" @primary:"true"`
 	Value string `@:"value"  @length:"2048"`
 }
+
+type MachineGroup struct {
+	Id        int64  `@:"id" @primary:"true" json:"-"`
+	MachineId int64  `@:"machine_id" json:"-"`
+	Name      string `@:"name" @length:"255" json:"name"`
+}
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -10,19 +11,23 @@ import (
 type NetworkAddCommand struct {
 	Name string `arg:"" name:"name" help:"Name of network."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
+func (cmd *NetworkAddCommand) Command() string {
+	return fmt.Sprint("docker network create --attachable --driver overlay --label rove --scope swarm ", shellescape.Quote(cmd.Name))
+}
+
 func (cmd *NetworkAddCommand) Do(conn SshRunner, stdin io.Reader) error {
 	fmt.Printf("\nRove will create the '%s' network.\n", cmd.Name)
 	if err := confirmDeployment(cmd.Force, stdin); err != nil {
 		return err
 	}
 	return conn.
-		Run(fmt.Sprint("docker network create --attachable --driver overlay --label rove --scope swarm ", shellescape.Quote(cmd.Name)), func(res string) error {
+		Run(cmd.Command(), func(res string) error {
 			fmt.Printf("\nCreated '%s' network.\n\n", cmd.Name)
 			return nil
 		}).
@@ -35,8 +40,8 @@ func (cmd *NetworkAddCommand) Do(conn SshRunner, stdin io.Reader) error {
 		Error()
 }
 
-func (cmd *NetworkAddCommand) Run() error {
+func (cmd *NetworkAddCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
 	})
 }
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -10,7 +11,7 @@ import (
 type NetworkDeleteCommand struct {
 	Name string `arg:"" name:"name" help:"Name of network."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
@@ -35,8 +36,8 @@ func (cmd *NetworkDeleteCommand) Do(conn SshRunner, stdin io.Reader) error {
 		Error()
 }
 
-func (cmd *NetworkDeleteCommand) Run() error {
+func (cmd *NetworkDeleteCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
 	})
 }
@@ -0,0 +1,78 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type DockerNodeLsJson struct {
+	Availability  string `json:"Availability"`
+	EngineVersion string `json:"EngineVersion"`
+	Hostname      string `json:"Hostname"`
+	Id            string `json:"ID"`
+	ManagerStatus string `json:"ManagerStatus"`
+	Status        string `json:"Status"`
+}
+
+type NodeListJson struct {
+	Nodes []NodeJson `json:"nodes"`
+}
+
+type NodeJson struct {
+	Availability  string `json:"availability"`
+	EngineVersion string `json:"engine_version"`
+	Hostname      string `json:"hostname"`
+	Id            string `json:"id"`
+	ManagerStatus string `json:"manager_status"`
+	Status        string `json:"status"`
+}
+
+type NodeListCommand struct {
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of manager machine." default:""`
+}
+
+func (cmd *NodeListCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	return conn.
+		Run("docker node ls --format json", func(res string) error {
+			output := NodeListJson{
+				Nodes: make([]NodeJson, 0),
+			}
+			for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+				if line != "" {
+					var dockerNodeLs DockerNodeLsJson
+					if err := json.Unmarshal([]byte(line), &dockerNodeLs); err != nil {
+						fmt.Fprintln(stdout, "🚫 Could not parse docker node ls JSON:\n", line)
+						return err
+					}
+					output.Nodes = append(output.Nodes, NodeJson(dockerNodeLs))
+				}
+			}
+			if cmd.Json {
+				out, err := json.MarshalIndent(output, "", "    ")
+				if err != nil {
+					fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+					return err
+				}
+				fmt.Fprintln(stdout, string(out))
+			} else {
+				for _, node := range output.Nodes {
+					fmt.Fprintln(stdout, node.Id, node.Hostname, node.Status, node.Availability, node.ManagerStatus)
+				}
+			}
+			return nil
+		}).
+		Error()
+}
+
+func (cmd *NodeListCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	}))
+}
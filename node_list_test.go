@@ -0,0 +1,21 @@
+package rove
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNodeListCommand(t *testing.T) {
+	mock := &SshConnectionMock{
+		Result: `{"Availability":"Active","EngineVersion":"27.0.3","Hostname":"manager-1","ID":"abc123","ManagerStatus":"Leader","Status":"Ready"}` + "\n" +
+			`{"Availability":"Drain","EngineVersion":"27.0.3","Hostname":"worker-1","ID":"def456","ManagerStatus":"","Status":"Ready"}` + "\n",
+	}
+	capture(t).
+		Run(func() error {
+			return (&NodeListCommand{}).Do(mock, nil)
+		}).
+		ExpectStdout("abc123 manager-1 Ready Active Leader\ndef456 worker-1 Ready Drain \n")
+	if !slices.Equal(mock.CommandsRun, []string{"docker node ls --format json"}) {
+		t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+	}
+}
@@ -0,0 +1,106 @@
+package rove
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alessio/shellescape"
+)
+
+type NodeDrainCommand struct {
+	Node string `arg:"" name:"node" help:"ID or hostname of node."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of manager machine." default:""`
+}
+
+func (cmd *NodeDrainCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return nodeUpdate(conn, stdin, cmd.Force, "drain", "drained", fmt.Sprint("docker node update --availability drain ", shellescape.Quote(cmd.Node)), cmd.Node)
+}
+
+func (cmd *NodeDrainCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	})
+}
+
+type NodePromoteCommand struct {
+	Node string `arg:"" name:"node" help:"ID or hostname of node."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of manager machine." default:""`
+}
+
+func (cmd *NodePromoteCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return nodeUpdate(conn, stdin, cmd.Force, "promote", "promoted", fmt.Sprint("docker node promote ", shellescape.Quote(cmd.Node)), cmd.Node)
+}
+
+func (cmd *NodePromoteCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	})
+}
+
+type NodeDemoteCommand struct {
+	Node string `arg:"" name:"node" help:"ID or hostname of node."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of manager machine." default:""`
+}
+
+func (cmd *NodeDemoteCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return nodeUpdate(conn, stdin, cmd.Force, "demote", "demoted", fmt.Sprint("docker node demote ", shellescape.Quote(cmd.Node)), cmd.Node)
+}
+
+func (cmd *NodeDemoteCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	})
+}
+
+type NodeRemoveCommand struct {
+	Node string `arg:"" name:"node" help:"ID or hostname of node."`
+
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine    string `flag:"" name:"machine" help:"Name of manager machine." default:""`
+}
+
+func (cmd *NodeRemoveCommand) Do(conn SshRunner, stdin io.Reader) error {
+	return nodeUpdate(conn, stdin, cmd.Force, "remove", "removed", fmt.Sprint("docker node rm ", shellescape.Quote(cmd.Node)), cmd.Node)
+}
+
+func (cmd *NodeRemoveCommand) Run(ctx context.Context) error {
+	return Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	})
+}
+
+// nodeUpdate confirms and runs a command that changes a swarm node. Docker refuses to remove nodes that are still active, so they should be drained and shut down first.
+func nodeUpdate(conn SshRunner, stdin io.Reader, force bool, action string, done string, command string, node string) error {
+	stdout := conn.Stdout()
+	fmt.Fprintf(stdout, "\nRove will %s node '%s'.\n", action, node)
+	if err := confirmDeploymentTo(stdout, force, stdin); err != nil {
+		return err
+	}
+	return conn.
+		Run(command, func(_ string) error {
+			fmt.Fprintf(stdout, "\nRove %s node '%s'.\n\n", done, node)
+			return nil
+		}).
+		OnError(func(err error) error {
+			if err != nil {
+				fmt.Fprintf(stdout, "🚫 Could not %s node '%s'\n", action, node)
+			}
+			return err
+		}).
+		Error()
+}
@@ -0,0 +1,35 @@
+package rove
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestNodeUpdateCommands(t *testing.T) {
+	tests := []struct {
+		cmd interface {
+			Do(SshRunner, io.Reader) error
+		}
+		command string
+		action  string
+		done    string
+	}{
+		{&NodeDrainCommand{Force: true, Node: "worker-1"}, "docker node update --availability drain worker-1", "drain", "drained"},
+		{&NodePromoteCommand{Force: true, Node: "worker-1"}, "docker node promote worker-1", "promote", "promoted"},
+		{&NodeDemoteCommand{Force: true, Node: "worker-1"}, "docker node demote worker-1", "demote", "demoted"},
+		{&NodeRemoveCommand{Force: true, Node: "worker-1"}, "docker node rm worker-1", "remove", "removed"},
+	}
+	for _, test := range tests {
+		mock := &SshConnectionMock{}
+		capture(t).
+			Run(func() error {
+				return test.cmd.Do(mock, nil)
+			}).
+			ExpectStdout(fmt.Sprintf("\nRove will %s node 'worker-1'.\n\nConfirmations skipped.\n\nRove %s node 'worker-1'.\n\n", test.action, test.done))
+		if !slices.Equal(mock.CommandsRun, []string{test.command}) {
+			t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package rove
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type Project struct {
+	Networks map[string]ProjectNetwork `toml:"networks" yaml:"networks"`
+	Secrets  []string                  `toml:"secrets" yaml:"secrets"`
+	Services map[string]ProjectService `toml:"services" yaml:"services"`
+	Tasks    map[string]ProjectTask    `toml:"tasks" yaml:"tasks"`
+	Volumes  map[string]ProjectVolume  `toml:"volumes" yaml:"volumes"`
+}
+
+type ProjectNetwork struct{}
+
+type ProjectService struct {
+	Command             []string `toml:"command" yaml:"command"`
+	Env                 []string `toml:"env" yaml:"env"`
+	Image               string   `toml:"image" yaml:"image"`
+	Init                bool     `toml:"init" yaml:"init"`
+	Mounts              []string `toml:"mounts" yaml:"mounts"`
+	Networks            []string `toml:"networks" yaml:"networks"`
+	Publish             []string `toml:"publish" yaml:"publish"`
+	Replicas            int64    `toml:"replicas" yaml:"replicas"`
+	Secrets             []string `toml:"secrets" yaml:"secrets"`
+	UpdateDelay         string   `toml:"update_delay" yaml:"update_delay"`
+	UpdateFailureAction string   `toml:"update_failure_action" yaml:"update_failure_action"`
+	UpdateOrder         string   `toml:"update_order" yaml:"update_order"`
+	UpdateParallelism   int64    `toml:"update_parallelism" yaml:"update_parallelism"`
+	User                string   `toml:"user" yaml:"user"`
+	WorkDir             string   `toml:"workdir" yaml:"workdir"`
+}
+
+type ProjectTask struct {
+	Command  []string `toml:"command" yaml:"command"`
+	Env      []string `toml:"env" yaml:"env"`
+	Image    string   `toml:"image" yaml:"image"`
+	Init     bool     `toml:"init" yaml:"init"`
+	Mounts   []string `toml:"mounts" yaml:"mounts"`
+	Networks []string `toml:"networks" yaml:"networks"`
+	Publish  []string `toml:"publish" yaml:"publish"`
+	Replicas int64    `toml:"replicas" yaml:"replicas"`
+	Secrets  []string `toml:"secrets" yaml:"secrets"`
+	User     string   `toml:"user" yaml:"user"`
+	WorkDir  string   `toml:"workdir" yaml:"workdir"`
+}
+
+type ProjectVolume struct {
+	Driver string   `toml:"driver" yaml:"driver"`
+	Opt    []string `toml:"opt" yaml:"opt"`
+}
+
+func ParseProject(file string) (*Project, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read project file: %v", err)
+	}
+	project := &Project{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".toml":
+		if _, err := toml.Decode(string(data), project); err != nil {
+			return nil, fmt.Errorf("unable to parse project file '%s': %v", file, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, project); err != nil {
+			return nil, fmt.Errorf("unable to parse project file '%s': %v", file, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported project file '%s'. Use a .toml or .yaml extension", file)
+	}
+	if err := project.Validate(); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (project *Project) ServiceRunCommands() []*ServiceRunCommand {
+	commands := make([]*ServiceRunCommand, 0)
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		service := project.Services[name]
+		commands = append(commands, &ServiceRunCommand{
+			Command:             service.Command,
+			Env:                 service.Env,
+			Image:               service.Image,
+			Init:                service.Init,
+			Mounts:              service.Mounts,
+			Name:                name,
+			Networks:            service.Networks,
+			Publish:             service.Publish,
+			Replicas:            ternary(service.Replicas == 0, 1, service.Replicas),
+			Secrets:             service.Secrets,
+			UpdateDelay:         service.UpdateDelay,
+			UpdateFailureAction: service.UpdateFailureAction,
+			UpdateOrder:         service.UpdateOrder,
+			UpdateParallelism:   ternary(service.UpdateParallelism == 0, 1, service.UpdateParallelism),
+			User:                service.User,
+			WorkDir:             service.WorkDir,
+		})
+	}
+	return commands
+}
+
+func (project *Project) TaskRunCommand(name string) (*TaskRunCommand, error) {
+	task, ok := project.Tasks[name]
+	if !ok {
+		return nil, fmt.Errorf("🚫 No task with name '%s' in project file", name)
+	}
+	return &TaskRunCommand{
+		Command:  task.Command,
+		Env:      task.Env,
+		Image:    task.Image,
+		Init:     task.Init,
+		Mounts:   task.Mounts,
+		Networks: task.Networks,
+		Publish:  task.Publish,
+		Replicas: ternary(task.Replicas == 0, 1, task.Replicas),
+		Secrets:  task.Secrets,
+		User:     task.User,
+		WorkDir:  task.WorkDir,
+	}, nil
+}
+
+func (project *Project) Validate() error {
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		if project.Services[name].Image == "" {
+			return fmt.Errorf("service '%s' in project file is missing an image", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(project.Tasks)) {
+		if project.Tasks[name].Image == "" {
+			return fmt.Errorf("task '%s' in project file is missing an image", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package rove
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseProject(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"rove.toml": `secrets = ["db_password"]
+
+[networks.backend]
+
+[volumes.data]
+driver = "local"
+
+[services.files]
+image = "python:3.12"
+command = ["python3", "-m", "http.server", "80"]
+networks = ["backend"]
+publish = ["80:80"]
+secrets = ["db_password"]
+
+[tasks.migrate]
+image = "python:3.12"
+command = ["python3", "migrate.py"]
+`,
+		"rove.yaml": `secrets: [db_password]
+networks:
+  backend: {}
+volumes:
+  data:
+    driver: local
+services:
+  files:
+    image: python:3.12
+    command: [python3, -m, http.server, "80"]
+    networks: [backend]
+    publish: ["80:80"]
+    secrets: [db_password]
+tasks:
+  migrate:
+    image: python:3.12
+    command: [python3, migrate.py]
+`,
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		project, err := ParseProject(file)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !slices.Equal(project.Secrets, []string{"db_password"}) {
+			t.Errorf("%s: secrets '%#v' did not match expected.", name, project.Secrets)
+		}
+		if _, ok := project.Networks["backend"]; !ok {
+			t.Errorf("%s: network 'backend' missing.", name)
+		}
+		if project.Volumes["data"].Driver != "local" {
+			t.Errorf("%s: volume '%#v' did not match expected.", name, project.Volumes["data"])
+		}
+
+		services := project.ServiceRunCommands()
+		if len(services) != 1 {
+			t.Fatalf("%s: expected 1 service, got %d.", name, len(services))
+		}
+		if services[0].Name != "files" || services[0].Image != "python:3.12" || services[0].Replicas != 1 || services[0].UpdateParallelism != 1 {
+			t.Errorf("%s: service '%#v' did not match expected.", name, services[0])
+		}
+		if !slices.Equal(services[0].Command, []string{"python3", "-m", "http.server", "80"}) {
+			t.Errorf("%s: command '%#v' did not match expected.", name, services[0].Command)
+		}
+
+		task, err := project.TaskRunCommand("migrate")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if task.Image != "python:3.12" || task.Replicas != 1 {
+			t.Errorf("%s: task '%#v' did not match expected.", name, task)
+		}
+	}
+}
+
+func TestParseProjectInvalid(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rove.toml")
+	if err := os.WriteFile(file, []byte("[services.files]\npublish = [\"80:80\"]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseProject(file); err == nil {
+		t.Error("expected error for service without image.")
+	}
+
+	file = filepath.Join(dir, "rove.json")
+	if err := os.WriteFile(file, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseProject(file); err == nil {
+		t.Error("expected error for unsupported extension.")
+	}
+}
@@ -0,0 +1,416 @@
+package rove
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OsRelease holds the fields of /etc/os-release that Rove uses to choose how to provision a machine.
+type OsRelease struct {
+	Id        string
+	IdLike    []string
+	VersionId string
+}
+
+const (
+	OsFamilyDebian = "debian"
+	OsFamilyRhel   = "rhel"
+)
+
+func osReleaseParse(res string) OsRelease {
+	var release OsRelease
+	for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, "'")
+		}
+		switch key {
+		case "ID":
+			release.Id = value
+		case "ID_LIKE":
+			release.IdLike = strings.Fields(value)
+		case "VERSION_ID":
+			release.VersionId = value
+		}
+	}
+	return release
+}
+
+// Family returns OsFamilyDebian or OsFamilyRhel, or an empty string for other distributions.
+func (release OsRelease) Family() string {
+	for _, id := range append([]string{release.Id}, release.IdLike...) {
+		switch id {
+		case "debian", "ubuntu":
+			return OsFamilyDebian
+		case "rhel", "fedora", "centos":
+			return OsFamilyRhel
+		}
+	}
+	return ""
+}
+
+// String is recorded on the machine, such as "rocky 9.4".
+func (release OsRelease) String() string {
+	return strings.TrimSpace(fmt.Sprint(release.Id, " ", release.VersionId))
+}
+
+const (
+	FirewallUfw       = "ufw"
+	FirewallFirewalld = "firewalld"
+)
+
+// provisioner holds the commands used to install and configure a machine. Packages is empty when Rove does not know how to install packages on the machine, and Firewall is empty when no supported firewall is available. Dnf5 is set when dnf is version 5, as on Fedora 41 and later, which configures its plugins differently.
+type provisioner struct {
+	Dnf5     bool
+	Firewall string
+	Packages string
+	Release  OsRelease
+}
+
+// provisionerDetect chooses the package manager and firewall for release. Machines running other distributions use whichever firewall is already installed.
+func provisionerDetect(conn SshRunner, release OsRelease) (*provisioner, error) {
+	p := &provisioner{
+		Release: release,
+	}
+	switch release.Family() {
+	case OsFamilyDebian:
+		p.Firewall = FirewallUfw
+		p.Packages = "apt-get"
+		return p, nil
+
+	case OsFamilyRhel:
+		p.Firewall = FirewallFirewalld
+		p.Packages = "dnf"
+		err := conn.
+			Run("command -v dnf", func(_ string) error {
+				return nil
+			}).
+			OnError(func(error) error {
+				p.Packages = "yum"
+				return ErrorSkip{}
+			}).
+			Run("command -v dnf5", func(_ string) error {
+				p.Dnf5 = true
+				return nil
+			}).
+			OnError(func(error) error {
+				return nil
+			}).
+			Error()
+		return p, err
+	}
+
+	for _, firewall := range []string{FirewallUfw, FirewallFirewalld} {
+		found := false
+		err := conn.
+			Run(fmt.Sprint("command -v ", provisionerFirewallBin(firewall)), func(_ string) error {
+				found = true
+				return nil
+			}).
+			OnError(func(error) error {
+				return nil
+			}).
+			Error()
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			p.Firewall = firewall
+			break
+		}
+	}
+	return p, nil
+}
+
+func provisionerFirewallBin(firewall string) string {
+	if firewall == FirewallFirewalld {
+		return "firewall-cmd"
+	}
+	return firewall
+}
+
+// FirewallCheck returns a command that fails when the firewall is not installed.
+func (p *provisioner) FirewallCheck() string {
+	return fmt.Sprint("command -v ", provisionerFirewallBin(p.Firewall))
+}
+
+// FirewallStatus returns a command that fails or prints something other than active when the firewall is not enabled.
+func (p *provisioner) FirewallStatus() (string, func(string) bool) {
+	if p.Firewall == FirewallFirewalld {
+		return "sudo firewall-cmd --state", func(res string) bool {
+			return strings.TrimSpace(res) == "running"
+		}
+	}
+	return "sudo ufw status", func(res string) bool {
+		return strings.HasPrefix(res, "Status: active")
+	}
+}
+
+func (p *provisioner) FirewallInstall() []string {
+	return []string{fmt.Sprintf("sudo %s install -y %s", p.Packages, p.Firewall)}
+}
+
+// FirewallEnable returns the commands that enable the firewall while keeping SSH on sshPort reachable.
+func (p *provisioner) FirewallEnable(sshPort int64) []string {
+	if p.Firewall == FirewallFirewalld {
+		return []string{
+			"sudo systemctl enable --now firewalld",
+			fmt.Sprintf("sudo firewall-cmd --permanent --add-port=%d/tcp", sshPort),
+			"sudo firewall-cmd --reload",
+		}
+	}
+	return []string{
+		"sudo ufw logging on",
+		fmt.Sprintf("sudo ufw allow %d/tcp", sshPort),
+		"sudo ufw --force enable",
+	}
+}
+
+// Fingerprints of the keys that sign Docker's package repositories. Via: https://docs.docker.com/engine/install/
+const (
+	dockerKeyApt = "9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+	dockerKeyRpm = "060A61C51B558A7F742B77AAC52FEB6B621E9F35"
+)
+
+var dockerVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+func dockerVersionValidate(version string) error {
+	if version != "" && !dockerVersionPattern.MatchString(version) {
+		return fmt.Errorf("🚫 Invalid Docker version '%s'. Expected a version such as 27.3.1", version)
+	}
+	return nil
+}
+
+// dockerRepo returns the directory of Docker's package repository for the distribution.
+func (p *provisioner) dockerRepo() string {
+	if p.Release.Family() == OsFamilyDebian {
+		if p.Release.Id == "ubuntu" || slices.Contains(p.Release.IdLike, "ubuntu") {
+			return "ubuntu"
+		}
+		return "debian"
+	}
+	switch p.Release.Id {
+	case "fedora", "rhel":
+		return p.Release.Id
+	}
+	return "centos"
+}
+
+// DockerRepoSetup returns the commands that add Docker's package repository after verifying the fingerprint of its signing key. They may be run again on machines that already have the repository.
+func (p *provisioner) DockerRepoSetup() ([]string, error) {
+	repo := p.dockerRepo()
+	switch p.Release.Family() {
+	case OsFamilyDebian:
+		// Via: https://docs.docker.com/engine/install/ubuntu/#install-using-the-repository
+		return []string{
+			"sudo apt-get update",
+			"sudo apt-get install -y ca-certificates curl gnupg",
+			fmt.Sprintf(`key=$(mktemp) && curl -fsSL https://download.docker.com/linux/%s/gpg -o "$key" && gpg --show-keys --with-colons "$key" | grep -q '^fpr:*%s:' && sudo install -D -m 0644 "$key" /etc/apt/keyrings/docker.asc; status=$?; rm -f "$key"; exit $status`, repo, dockerKeyApt),
+			fmt.Sprintf(`echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.asc] https://download.docker.com/linux/%s $(. /etc/os-release && echo "${UBUNTU_CODENAME:-$VERSION_CODENAME}") stable" | sudo tee /etc/apt/sources.list.d/docker.list > /dev/null`, repo),
+			"sudo apt-get update",
+		}, nil
+	case OsFamilyRhel:
+		// Via: https://docs.docker.com/engine/install/centos/#install-using-the-repository
+		return []string{
+			fmt.Sprintf(`key=$(mktemp) && curl -fsSL https://download.docker.com/linux/%s/gpg -o "$key" && gpg --show-keys --with-colons "$key" | grep -q '^fpr:*%s:' && sudo rpm --import "$key"; status=$?; rm -f "$key"; exit $status`, repo, dockerKeyRpm),
+			fmt.Sprintf("sudo curl -fsSL -o /etc/yum.repos.d/docker-ce.repo https://download.docker.com/linux/%s/docker-ce.repo", repo),
+		}, nil
+	}
+	return nil, fmt.Errorf("🚫 Installing Docker is not supported on OS '%s'. Install Docker manually and rerun this command with --skip", p.Release)
+}
+
+// DockerInstall returns the commands that install Docker from the repository added by DockerRepoSetup, or upgrade it in place. An empty version installs the latest release. Pinned versions are held on apt, and locked with the versionlock plugin on dnf and yum, so that automatic updates do not replace them.
+func (p *provisioner) DockerInstall(version string) ([]string, error) {
+	if err := dockerVersionValidate(version); err != nil {
+		return nil, err
+	}
+	plugins := "containerd.io docker-buildx-plugin docker-compose-plugin"
+	switch p.Release.Family() {
+	case OsFamilyDebian:
+		if version == "" {
+			return []string{
+				fmt.Sprint("sudo apt-get install -y --allow-change-held-packages docker-ce docker-ce-cli ", plugins),
+				"sudo apt-mark unhold docker-ce docker-ce-cli",
+			}, nil
+		}
+		return []string{
+			fmt.Sprintf(`v=$(apt-cache madison docker-ce | awk '{print $3}' | grep -m 1 -F ':%s-') && test -n "$v" && sudo apt-get install -y --allow-downgrades --allow-change-held-packages docker-ce="$v" docker-ce-cli="$v" %s`, version, plugins),
+			"sudo apt-mark hold docker-ce docker-ce-cli",
+		}, nil
+	case OsFamilyRhel:
+		// Locks from an earlier pin would otherwise prevent installing another version.
+		unlock := fmt.Sprint("sudo ", p.Packages, " versionlock delete docker-ce docker-ce-cli || true")
+		if version == "" {
+			return []string{
+				unlock,
+				fmt.Sprintf("sudo %s install -y docker-ce docker-ce-cli %s || sudo %s downgrade -y docker-ce docker-ce-cli", p.Packages, plugins, p.Packages),
+				"sudo systemctl enable --now docker",
+			}, nil
+		}
+		packages := fmt.Sprintf("docker-ce-%s docker-ce-cli-%s", version, version)
+		commands := []string{}
+		if plugin := p.versionlockPlugin(); plugin != "" {
+			commands = append(commands, fmt.Sprint("sudo ", p.Packages, " install -y ", plugin))
+		}
+		return append(commands,
+			unlock,
+			fmt.Sprintf("sudo %s install -y %s %s || sudo %s downgrade -y %s", p.Packages, packages, plugins, p.Packages, packages),
+			fmt.Sprint("sudo ", p.Packages, " versionlock add docker-ce docker-ce-cli"),
+			"sudo systemctl enable --now docker",
+		), nil
+	}
+	return nil, fmt.Errorf("🚫 Installing Docker is not supported on OS '%s'. Install Docker manually and rerun this command with --skip", p.Release)
+}
+
+// versionlockPlugin is the package that provides the versionlock command. It is built into dnf5.
+func (p *provisioner) versionlockPlugin() string {
+	switch {
+	case p.Dnf5:
+		return ""
+	case p.Packages == "yum":
+		return "yum-plugin-versionlock"
+	}
+	return "python3-dnf-plugin-versionlock"
+}
+
+// provisionRun runs commands in order, printing each with its output.
+func provisionRun(conn SshRunner, commands []string) error {
+	for _, command := range commands {
+		err := conn.
+			Run(command, func(res string) error {
+				fmt.Println(command, res)
+				return nil
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// provisionRunQuiet runs commands in order without printing their output, which is long for package installs.
+func provisionRunQuiet(conn SshRunner, commands []string) error {
+	for _, command := range commands {
+		err := conn.
+			Run(command, func(_ string) error {
+				return nil
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Updates returns the name of the package that installs automatic updates, or an empty string when the machine is not supported.
+func (p *provisioner) Updates() string {
+	switch {
+	case p.Release.Family() == OsFamilyDebian:
+		return "unattended-upgrades"
+	case p.Release.Family() == OsFamilyRhel && p.Packages == "dnf":
+		return "dnf-automatic"
+	}
+	return ""
+}
+
+// UpdatesCheck returns a command that fails when automatic updates are not installed. On dnf5 the package is named dnf5-plugin-automatic, which provides dnf-automatic.
+func (p *provisioner) UpdatesCheck() string {
+	if p.Updates() == "dnf-automatic" {
+		return "rpm -q --whatprovides dnf-automatic"
+	}
+	return "command -v unattended-upgrade"
+}
+
+// updatesTimer returns the systemd timer that runs dnf-automatic.
+func (p *provisioner) updatesTimer() string {
+	if p.Dnf5 {
+		return "dnf5-automatic.timer"
+	}
+	return "dnf-automatic.timer"
+}
+
+// UpdatesStatus returns a command that fails or prints something that is not enabled when automatic updates are disabled.
+func (p *provisioner) UpdatesStatus() (string, func(string) bool) {
+	if p.Updates() == "dnf-automatic" {
+		return fmt.Sprint("systemctl is-enabled ", p.updatesTimer()), func(res string) bool {
+			return strings.TrimSpace(res) == "enabled"
+		}
+	}
+	return "apt-config dump APT::Periodic::Unattended-Upgrade", func(res string) bool {
+		return strings.Contains(res, `APT::Periodic::Unattended-Upgrade "1";`)
+	}
+}
+
+// UpdatesInstall returns the commands that install automatic updates. The apt package lists are refreshed first, since they may be missing or stale on new machines.
+func (p *provisioner) UpdatesInstall() []string {
+	if p.Packages == "apt-get" {
+		return []string{
+			"sudo apt-get update",
+			fmt.Sprint("sudo apt-get install -y ", p.Updates()),
+		}
+	}
+	return []string{fmt.Sprintf("sudo %s install -y %s", p.Packages, p.Updates())}
+}
+
+// UpdatesEnable returns the commands that enable daily security updates. Debian and Ubuntu only install security updates with the default unattended-upgrades configuration. dnf5 does not install /etc/dnf/automatic.conf, so its settings are written to a drop-in instead.
+func (p *provisioner) UpdatesEnable() []string {
+	if p.Updates() == "dnf-automatic" && p.Dnf5 {
+		return []string{
+			"sudo mkdir -p /etc/dnf/libdnf5-plugins/actions.d",
+			`printf '[commands]\nupgrade_type = security\napply_updates = yes\n' | sudo tee /etc/dnf/libdnf5-plugins/actions.d/automatic.conf > /dev/null`,
+			fmt.Sprint("sudo systemctl enable --now ", p.updatesTimer()),
+		}
+	}
+	if p.Updates() == "dnf-automatic" {
+		return []string{
+			`sudo sed -i -e 's/^upgrade_type\s*=.*/upgrade_type = security/' -e 's/^apply_updates\s*=.*/apply_updates = yes/' /etc/dnf/automatic.conf`,
+			fmt.Sprint("sudo systemctl enable --now ", p.updatesTimer()),
+		}
+	}
+	return []string{
+		`printf 'APT::Periodic::Update-Package-Lists "1";\nAPT::Periodic::Unattended-Upgrade "1";\n' | sudo tee /etc/apt/apt.conf.d/20auto-upgrades > /dev/null`,
+	}
+}
+
+func (p *provisioner) UpdatesDisable() []string {
+	if p.Updates() == "dnf-automatic" {
+		return []string{fmt.Sprint("sudo systemctl disable --now ", p.updatesTimer())}
+	}
+	return []string{
+		`printf 'APT::Periodic::Update-Package-Lists "0";\nAPT::Periodic::Unattended-Upgrade "0";\n' | sudo tee /etc/apt/apt.conf.d/20auto-upgrades > /dev/null`,
+	}
+}
+
+// updatesProbe reports whether automatic updates are installed and enabled.
+func updatesProbe(conn SshRunner, p *provisioner) (bool, bool, error) {
+	installed := true
+	enabled := false
+	updatesStatus, updatesEnabled := p.UpdatesStatus()
+	err := conn.
+		Run(p.UpdatesCheck(), func(_ string) error {
+			return nil
+		}).
+		OnError(func(error) error {
+			installed = false
+			return ErrorSkip{}
+		}).
+		Run(updatesStatus, func(res string) error {
+			enabled = updatesEnabled(res)
+			return nil
+		}).
+		OnError(func(error) error {
+			return nil
+		}).
+		Error()
+	return installed, enabled, err
+}
@@ -0,0 +1,247 @@
+package rove
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOsReleaseParse(t *testing.T) {
+	release := osReleaseParse(`NAME="Rocky Linux"
+VERSION="9.4 (Blue Onyx)"
+ID="rocky"
+ID_LIKE="rhel centos fedora"
+VERSION_ID="9.4"
+`)
+	if release.Id != "rocky" || release.VersionId != "9.4" || !slices.Equal(release.IdLike, []string{"rhel", "centos", "fedora"}) {
+		t.Errorf("'%#v' did not match expected.", release)
+	}
+	if release.String() != "rocky 9.4" {
+		t.Errorf("'%s' did not match expected.", release)
+	}
+
+	tests := map[string]string{
+		"ID=ubuntu\nVERSION_ID=\"24.04\"\nID_LIKE=debian\n": OsFamilyDebian,
+		"ID=debian\nVERSION_ID=\"12\"\n":                    OsFamilyDebian,
+		"ID=fedora\nVERSION_ID=40\n":                        OsFamilyRhel,
+		"ID=\"almalinux\"\nID_LIKE=\"rhel centos fedora\"":  OsFamilyRhel,
+		"ID=alpine\nVERSION_ID=3.20.0\n":                    "",
+	}
+	for res, expected := range tests {
+		if family := osReleaseParse(res).Family(); family != expected {
+			t.Errorf("'%s' family '%s' did not match expected '%s'.", res, family, expected)
+		}
+	}
+}
+
+func TestProvisionerDetect(t *testing.T) {
+	mock := &SshConnectionMock{
+		Errors: map[string]error{
+			"command -v dnf": errors.New("not found"),
+		},
+	}
+	p, err := provisionerDetect(mock, OsRelease{Id: "centos", VersionId: "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Firewall != FirewallFirewalld || p.Packages != "yum" || p.Dnf5 {
+		t.Errorf("'%#v' did not match expected.", p)
+	}
+
+	mock = &SshConnectionMock{}
+	p, err = provisionerDetect(mock, OsRelease{Id: "fedora", VersionId: "41"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Packages != "dnf" || !p.Dnf5 {
+		t.Errorf("'%#v' did not match expected.", p)
+	}
+
+	mock = &SshConnectionMock{
+		Errors: map[string]error{
+			"command -v ufw": errors.New("not found"),
+		},
+	}
+	p, err = provisionerDetect(mock, OsRelease{Id: "alpine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Firewall != FirewallFirewalld || p.Packages != "" {
+		t.Errorf("'%#v' did not match expected.", p)
+	}
+}
+
+func TestProvisionerCommands(t *testing.T) {
+	p := &provisioner{Firewall: FirewallFirewalld, Packages: "dnf", Release: OsRelease{Id: "rocky", IdLike: []string{"rhel"}}}
+	expected := []string{
+		"sudo dnf versionlock delete docker-ce docker-ce-cli || true",
+		"sudo dnf install -y docker-ce docker-ce-cli containerd.io docker-buildx-plugin docker-compose-plugin || sudo dnf downgrade -y docker-ce docker-ce-cli",
+		"sudo systemctl enable --now docker",
+	}
+	if commands, err := p.DockerInstall(""); err != nil || !slices.Equal(commands, expected) {
+		t.Errorf("'%#v' did not match expected: %v", commands, err)
+	}
+	expected = []string{
+		"sudo dnf install -y python3-dnf-plugin-versionlock",
+		"sudo dnf versionlock delete docker-ce docker-ce-cli || true",
+		"sudo dnf install -y docker-ce-27.3.1 docker-ce-cli-27.3.1 containerd.io docker-buildx-plugin docker-compose-plugin || sudo dnf downgrade -y docker-ce-27.3.1 docker-ce-cli-27.3.1",
+		"sudo dnf versionlock add docker-ce docker-ce-cli",
+		"sudo systemctl enable --now docker",
+	}
+	if commands, err := p.DockerInstall("27.3.1"); err != nil || !slices.Equal(commands, expected) {
+		t.Errorf("'%#v' did not match expected: %v", commands, err)
+	}
+	if commands, _ := (&provisioner{Packages: "yum", Release: p.Release}).DockerInstall("27.3.1"); commands[0] != "sudo yum install -y yum-plugin-versionlock" || commands[3] != "sudo yum versionlock add docker-ce docker-ce-cli" {
+		t.Errorf("'%#v' did not match expected.", commands)
+	}
+	if commands, _ := (&provisioner{Dnf5: true, Packages: "dnf", Release: p.Release}).DockerInstall("27.3.1"); commands[0] != "sudo dnf versionlock delete docker-ce docker-ce-cli || true" {
+		t.Errorf("'%#v' did not match expected.", commands)
+	}
+	commands, err := p.DockerRepoSetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(commands[0], "https://download.docker.com/linux/centos/gpg") || !strings.Contains(commands[0], dockerKeyRpm) {
+		t.Errorf("'%s' does not verify the key fingerprint.", commands[0])
+	}
+	if commands[1] != "sudo curl -fsSL -o /etc/yum.repos.d/docker-ce.repo https://download.docker.com/linux/centos/docker-ce.repo" {
+		t.Errorf("'%s' did not match expected.", commands[1])
+	}
+	expected = []string{
+		"sudo systemctl enable --now firewalld",
+		"sudo firewall-cmd --permanent --add-port=2222/tcp",
+		"sudo firewall-cmd --reload",
+	}
+	if commands := p.FirewallEnable(2222); !slices.Equal(commands, expected) {
+		t.Errorf("'%#v' did not match expected.", commands)
+	}
+	if commands := p.FirewallInstall(); !slices.Equal(commands, []string{"sudo dnf install -y firewalld"}) {
+		t.Errorf("'%#v' did not match expected.", commands)
+	}
+	if commands := p.UpdatesInstall(); !slices.Equal(commands, []string{"sudo dnf install -y dnf-automatic"}) {
+		t.Errorf("'%#v' did not match expected.", commands)
+	}
+	if commands := p.UpdatesDisable(); !slices.Equal(commands, []string{"sudo systemctl disable --now dnf-automatic.timer"}) {
+		t.Errorf("'%#v' did not match expected.", commands)
+	}
+	if p := (&provisioner{Packages: "yum", Release: p.Release}); p.Updates() != "" {
+		t.Errorf("'%s' did not match expected.", p.Updates())
+	}
+
+	p = &provisioner{Firewall: FirewallUfw, Packages: "apt-get", Release: OsRelease{Id: "ubuntu"}}
+	commands, err = p.DockerRepoSetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(commands[2], "https://download.docker.com/linux/ubuntu/gpg") || !strings.Contains(commands[2], dockerKeyApt) {
+		t.Errorf("'%s' does not verify the key fingerprint.", commands[2])
+	}
+	if commands, err := p.DockerInstall("27.3.1"); err != nil || !strings.Contains(commands[0], "':27.3.1-'") || commands[1] != "sudo apt-mark hold docker-ce docker-ce-cli" {
+		t.Errorf("'%#v' did not match expected: %v", commands, err)
+	}
+	if commands, err := p.DockerInstall(""); err != nil || commands[1] != "sudo apt-mark unhold docker-ce docker-ce-cli" {
+		t.Errorf("'%#v' did not match expected: %v", commands, err)
+	}
+	if _, err := p.DockerInstall("27.3"); err == nil {
+		t.Error("Expected invalid version to fail.")
+	}
+
+	if p.Updates() != "unattended-upgrades" {
+		t.Errorf("'%s' did not match expected.", p.Updates())
+	}
+
+	p = &provisioner{Release: OsRelease{Id: "alpine"}}
+	if _, err := p.DockerRepoSetup(); err == nil {
+		t.Error("Expected unsupported OS to fail.")
+	}
+}
+
+func TestProvisionerUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       *provisioner
+		install []string
+		enable  []string
+		disable []string
+		check   string
+		status  string
+	}{
+		{
+			name:    "debian",
+			p:       &provisioner{Packages: "apt-get", Release: OsRelease{Id: "debian"}},
+			install: []string{"sudo apt-get update", "sudo apt-get install -y unattended-upgrades"},
+			enable:  []string{`printf 'APT::Periodic::Update-Package-Lists "1";\nAPT::Periodic::Unattended-Upgrade "1";\n' | sudo tee /etc/apt/apt.conf.d/20auto-upgrades > /dev/null`},
+			disable: []string{`printf 'APT::Periodic::Update-Package-Lists "0";\nAPT::Periodic::Unattended-Upgrade "0";\n' | sudo tee /etc/apt/apt.conf.d/20auto-upgrades > /dev/null`},
+			check:   "command -v unattended-upgrade",
+			status:  "apt-config dump APT::Periodic::Unattended-Upgrade",
+		},
+		{
+			name:    "rhel",
+			p:       &provisioner{Packages: "dnf", Release: OsRelease{Id: "rocky", IdLike: []string{"rhel"}}},
+			install: []string{"sudo dnf install -y dnf-automatic"},
+			enable: []string{
+				`sudo sed -i -e 's/^upgrade_type\s*=.*/upgrade_type = security/' -e 's/^apply_updates\s*=.*/apply_updates = yes/' /etc/dnf/automatic.conf`,
+				"sudo systemctl enable --now dnf-automatic.timer",
+			},
+			disable: []string{"sudo systemctl disable --now dnf-automatic.timer"},
+			check:   "rpm -q --whatprovides dnf-automatic",
+			status:  "systemctl is-enabled dnf-automatic.timer",
+		},
+		{
+			name:    "fedora dnf5",
+			p:       &provisioner{Dnf5: true, Packages: "dnf", Release: OsRelease{Id: "fedora", VersionId: "41"}},
+			install: []string{"sudo dnf install -y dnf-automatic"},
+			enable: []string{
+				"sudo mkdir -p /etc/dnf/libdnf5-plugins/actions.d",
+				`printf '[commands]\nupgrade_type = security\napply_updates = yes\n' | sudo tee /etc/dnf/libdnf5-plugins/actions.d/automatic.conf > /dev/null`,
+				"sudo systemctl enable --now dnf5-automatic.timer",
+			},
+			disable: []string{"sudo systemctl disable --now dnf5-automatic.timer"},
+			check:   "rpm -q --whatprovides dnf-automatic",
+			status:  "systemctl is-enabled dnf5-automatic.timer",
+		},
+	}
+	for _, test := range tests {
+		if commands := test.p.UpdatesInstall(); !slices.Equal(commands, test.install) {
+			t.Errorf("%s: '%#v' did not match expected install.", test.name, commands)
+		}
+		if commands := test.p.UpdatesEnable(); !slices.Equal(commands, test.enable) {
+			t.Errorf("%s: '%#v' did not match expected enable.", test.name, commands)
+		}
+		if commands := test.p.UpdatesDisable(); !slices.Equal(commands, test.disable) {
+			t.Errorf("%s: '%#v' did not match expected disable.", test.name, commands)
+		}
+		if command := test.p.UpdatesCheck(); command != test.check {
+			t.Errorf("%s: '%s' did not match expected check.", test.name, command)
+		}
+		if command, _ := test.p.UpdatesStatus(); command != test.status {
+			t.Errorf("%s: '%s' did not match expected status.", test.name, command)
+		}
+	}
+
+	for _, p := range []*provisioner{
+		{Packages: "yum", Release: OsRelease{Id: "centos", VersionId: "7"}},
+		{Release: OsRelease{Id: "alpine"}},
+	} {
+		if p.Updates() != "" {
+			t.Errorf("'%s' did not match expected.", p.Updates())
+		}
+	}
+}
+
+func TestDockerUpgrade(t *testing.T) {
+	mock := &SshConnectionMock{Result: "27.3.1\n"}
+	capture(t).
+		Run(func() error {
+			if err := dockerUpgrade(mock, nil, true, "27.3.1"); !errors.Is(err, ErrorSkip{}) {
+				return fmt.Errorf("expected ErrorSkip, got %v", err)
+			}
+			return nil
+		}).
+		ExpectStdout("\nDocker 27.3.1 already installed.\n\nNo changes needed.\n\n")
+	if !slices.Equal(mock.CommandsRun, []string{"docker version --format '{{.Server.Version}}'"}) {
+		t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+	}
+}
@@ -1,6 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/alecthomas/kong"
 	"github.com/evantbyrne/rove"
 	"github.com/evantbyrne/trance"
@@ -8,6 +15,22 @@ import (
 )
 
 var cli struct {
+	CommandTimeout time.Duration `name:"command-timeout" help:"Timeout for each remote command. Disabled when zero." default:"0s" env:"ROVE_COMMAND_TIMEOUT"`
+	ConnectRetries int           `name:"connect-retries" help:"Times to retry machines that time out or are unreachable, and read-only commands that lose their connection." default:"2" env:"ROVE_CONNECT_RETRIES"`
+	ConnectTimeout time.Duration `name:"connect-timeout" help:"Timeout for establishing SSH connections." default:"15s" env:"ROVE_CONNECT_TIMEOUT"`
+	Keepalive      time.Duration `name:"keepalive" help:"Interval between SSH keepalives. Disabled when zero." default:"30s" env:"ROVE_KEEPALIVE"`
+
+	Apply  rove.ApplyCommand `cmd:"" help:"Apply a project file."`
+	Config struct {
+		Export rove.ConfigExportCommand `cmd:"" help:"Export machines and preferences as JSON or YAML."`
+		Import rove.ConfigImportCommand `cmd:"" help:"Merge machines and preferences from an export."`
+	} `cmd:"" help:"Export and import configuration."`
+	Firewall struct {
+		Allow rove.FirewallAllowCommand `cmd:"" help:"Add ufw rule allowing port."`
+		Audit rove.FirewallAuditCommand `cmd:"" help:"Compare ufw rules against ports published by services."`
+		Deny  rove.FirewallDenyCommand  `cmd:"" help:"Add ufw rule denying port."`
+		List  rove.FirewallListCommand  `cmd:""`
+	} `cmd:"" help:"Manage firewall rules."`
 	Inspect rove.InspectCommand `cmd:"" help:"Inspect services and tasks."`
 	Login   rove.LoginCommand   `cmd:"" help:"Log into docker registries."`
 	Logout  rove.LogoutCommand  `cmd:"" help:"Log out of docker registries."`
@@ -15,14 +38,35 @@ var cli struct {
 	Machine struct {
 		Add    rove.MachineAddCommand    `cmd:""`
 		Delete rove.MachineDeleteCommand `cmd:""`
-		List   rove.MachineListCommand   `cmd:""`
-		Use    rove.MachineUseCommand    `cmd:""`
+		Doctor rove.MachineDoctorCommand `cmd:"" help:"Check health of machine."`
+		Edit   rove.MachineEditCommand   `cmd:"" help:"Change address, user, port, key or name of machine."`
+		Group  struct {
+			Add    rove.MachineGroupAddCommand    `cmd:""`
+			List   rove.MachineGroupListCommand   `cmd:""`
+			Remove rove.MachineGroupRemoveCommand `cmd:""`
+		} `cmd:"" help:"Manage machine groups."`
+		List    rove.MachineListCommand  `cmd:""`
+		Rekey   rove.MachineRekeyCommand `cmd:""`
+		Updates struct {
+			Disable rove.MachineUpdatesDisableCommand `cmd:"" help:"Disable automatic security updates."`
+			Enable  rove.MachineUpdatesEnableCommand  `cmd:"" help:"Enable automatic security updates."`
+			Status  rove.MachineUpdatesStatusCommand  `cmd:"" help:"Show whether automatic security updates are enabled."`
+		} `cmd:"" help:"Manage automatic OS security updates."`
+		UpgradeDocker rove.MachineUpgradeDockerCommand `cmd:"" help:"Upgrade or pin Docker on machine."`
+		Use           rove.MachineUseCommand           `cmd:""`
 	} `cmd:"" help:"Manage machines."`
 	Network struct {
 		Add    rove.NetworkAddCommand    `cmd:""`
 		Delete rove.NetworkDeleteCommand `cmd:""`
 		List   rove.NetworkListCommand   `cmd:""`
 	} `cmd:"" help:"Manage networks."`
+	Node struct {
+		Demote  rove.NodeDemoteCommand  `cmd:"" help:"Demote manager node to worker."`
+		Drain   rove.NodeDrainCommand   `cmd:"" help:"Move tasks off node."`
+		List    rove.NodeListCommand    `cmd:""`
+		Promote rove.NodePromoteCommand `cmd:"" help:"Promote worker node to manager."`
+		Remove  rove.NodeRemoveCommand  `cmd:"" help:"Remove node from swarm."`
+	} `cmd:"" help:"Manage swarm nodes."`
 	Secret struct {
 		Create rove.SecretCreateCommand `cmd:""`
 		Delete rove.SecretDeleteCommand `cmd:""`
@@ -31,6 +75,7 @@ var cli struct {
 	Service struct {
 		Delete   rove.ServiceDeleteCommand   `cmd:""`
 		List     rove.ServiceListCommand     `cmd:""`
+		Plan     rove.ServicePlanCommand     `cmd:"" help:"Show deployment plan without applying it. Exits with status 2 when changes are pending."`
 		Redeploy rove.ServiceRedeployCommand `cmd:""`
 		Rollback rove.ServiceRollbackCommand `cmd:""`
 		Run      rove.ServiceRunCommand      `cmd:""`
@@ -49,7 +94,27 @@ var cli struct {
 
 func main() {
 	trance.SetDialect(sqlitedialect.SqliteDialect{})
-	ctx := kong.Parse(&cli, kong.UsageOnError())
+	// Commands receive a context that is canceled on SIGINT or SIGTERM, which stops remote commands instead of leaving them running.
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signalCtx.Done()
+		// A second signal terminates immediately, and work that ignores cancellation, such as confirmation prompts, is abandoned after a grace period.
+		stop()
+		time.Sleep(3 * time.Second)
+		os.Exit(130)
+	}()
+	ctx := kong.Parse(&cli, kong.UsageOnError(), kong.BindTo(signalCtx, (*context.Context)(nil)))
+	rove.SshConnectDefaults.CommandTimeout = cli.CommandTimeout
+	rove.SshConnectDefaults.Keepalive = cli.Keepalive
+	rove.SshConnectDefaults.Retries = cli.ConnectRetries
+	rove.SshConnectDefaults.Timeout = cli.ConnectTimeout
 	err := ctx.Run()
+	var errExit rove.ErrorExit
+	if errors.As(err, &errExit) {
+		os.Exit(errExit.Code)
+	}
+	if errors.Is(err, context.Canceled) {
+		os.Exit(130)
+	}
 	ctx.FatalIfErrorf(err)
 }
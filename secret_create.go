@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,7 +16,7 @@ type SecretCreateCommand struct {
 	Name string   `arg:"" name:"name" help:"Name of secret."`
 	File *os.File `arg:"" name:"file" help:"Secret file. Use dash (-) to read from STDIN."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
@@ -71,7 +72,9 @@ func (cmd *SecretCreateCommand) Do(conn SshRunner, stdin io.Reader) error {
 		}).
 		OnError(func(err error) error {
 			if err != nil {
-				fmt.Println("\n🚫 Could not add secret")
+				if !cmd.Json {
+					fmt.Println("\n🚫 Could not add secret")
+				}
 			}
 			return err
 		}).
@@ -84,8 +87,8 @@ func (cmd *SecretCreateCommand) Do(conn SshRunner, stdin io.Reader) error {
 	return err
 }
 
-func (cmd *SecretCreateCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
-	})
+func (cmd *SecretCreateCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
+	}))
 }
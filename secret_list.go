@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,10 +16,12 @@ type DockerSecretLsJson struct {
 }
 
 type SecretListCommand struct {
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
-	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
-	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Json        bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
 type SecretListJson struct {
@@ -32,9 +35,10 @@ type SecretJson struct {
 	UpdatedAt string `json:"updated_at"`
 }
 
-func (cmd *SecretListCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+func (cmd *SecretListCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, func(conn SshRunner, stdin io.Reader) error {
+			stdout := conn.Stdout()
 			return conn.
 				Run("docker secret ls --format json --filter label=rove", func(res string) error {
 					output := make([]DockerSecretLsJson, 0)
@@ -42,7 +46,7 @@ func (cmd *SecretListCommand) Run() error {
 						if line != "" {
 							var dockerSecretLs DockerSecretLsJson
 							if err := json.Unmarshal([]byte(line), &dockerSecretLs); err != nil {
-								fmt.Println("🚫 Could not parse docker secret ls JSON:\n", line)
+								fmt.Fprintln(stdout, "🚫 Could not parse docker secret ls JSON:\n", line)
 								return err
 							}
 							output = append(output, dockerSecretLs)
@@ -55,18 +59,18 @@ func (cmd *SecretListCommand) Run() error {
 						}
 						out, err := json.MarshalIndent(t, "", "    ")
 						if err != nil {
-							fmt.Println("🚫 Could not format JSON:\n", t)
+							fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", t)
 							return err
 						}
-						fmt.Println(string(out))
+						fmt.Fprintln(stdout, string(out))
 					} else {
 						for _, dockerSecretLs := range output {
-							fmt.Println(dockerSecretLs.Id, dockerSecretLs.Name, dockerSecretLs.CreatedAt, dockerSecretLs.UpdatedAt)
+							fmt.Fprintln(stdout, dockerSecretLs.Id, dockerSecretLs.Name, dockerSecretLs.CreatedAt, dockerSecretLs.UpdatedAt)
 						}
 					}
 					return nil
 				}).
 				Error()
 		})
-	})
+	}))
 }
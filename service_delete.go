@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,15 +13,15 @@ import (
 type ServiceDeleteCommand struct {
 	Name string `arg:"" name:"name" help:"Name of service."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
-func (cmd *ServiceDeleteCommand) Run() error {
+func (cmd *ServiceDeleteCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
 			old := &ServiceState{
 				Command: make([]string, 0),
 				Publish: make([]string, 0),
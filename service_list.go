@@ -1,10 +1,13 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/alessio/shellescape"
 )
 
 type DockerServiceLsJson struct {
@@ -15,10 +18,12 @@ type DockerServiceLsJson struct {
 }
 
 type ServiceListCommand struct {
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
-	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
-	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Json        bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
 type ServiceListJson struct {
@@ -40,9 +45,10 @@ type ServiceListPortJson struct {
 	PublishMode   string `json:"publish_mode"`
 }
 
-func (cmd *ServiceListCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+func (cmd *ServiceListCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, func(conn SshRunner, stdin io.Reader) error {
+			stdout := conn.Stdout()
 			output := ServiceListJson{
 				Services: make([]ServiceListEntryJson, 0),
 			}
@@ -52,7 +58,7 @@ func (cmd *ServiceListCommand) Run() error {
 					if line != "" {
 						var dockerServiceLs DockerServiceLsJson
 						if err := json.Unmarshal([]byte(line), &dockerServiceLs); err != nil {
-							fmt.Println("🚫 Could not parse docker service ls JSON:\n", line)
+							fmt.Fprintln(stdout, "🚫 Could not parse docker service ls JSON:\n", line)
 							return err
 						}
 						output.Services = append(output.Services, ServiceListEntryJson{
@@ -69,39 +75,60 @@ func (cmd *ServiceListCommand) Run() error {
 				return err
 			}
 
-			for i, service := range output.Services {
-				if err := conn.Run(fmt.Sprint("docker service inspect ", service.Name), func(res string) error {
-					var dockerInspect []DockerServiceInspectJson
-					if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
-						fmt.Println("🚫 Could not parse docker service inspect JSON:\n", res)
-						return err
-					}
-					for _, entry := range dockerInspect[0].Spec.EndpointSpec.Ports {
+			names := make([]string, 0, len(output.Services))
+			for _, service := range output.Services {
+				names = append(names, service.Name)
+			}
+			if err := dockerServiceInspectAll(conn, names, func(dockerInspect []DockerServiceInspectJson) error {
+				for i, inspect := range dockerInspect {
+					for _, entry := range inspect.Spec.EndpointSpec.Ports {
 						output.Services[i].Ports = append(output.Services[i].Ports, ServiceListPortJson(entry))
 					}
-					return nil
-				}).Error(); err != nil {
-					return err
 				}
+				return nil
+			}).Error(); err != nil {
+				return err
 			}
 
 			if cmd.Json {
 				out, err := json.MarshalIndent(output, "", "    ")
 				if err != nil {
-					fmt.Println("🚫 Could not format JSON:\n", output)
+					fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
 					return err
 				}
-				fmt.Println(string(out))
+				fmt.Fprintln(stdout, string(out))
 			} else {
 				for _, service := range output.Services {
 					ports := []string{}
 					for _, entry := range service.Ports {
 						ports = append(ports, fmt.Sprintf("%d:%d/%s", entry.TargetPort, entry.PublishedPort, entry.Protocol))
 					}
-					fmt.Println(service.Id, service.Name, service.Image, service.Replicas, strings.Join(ports, ","))
+					fmt.Fprintln(stdout, service.Id, service.Name, service.Image, service.Replicas, strings.Join(ports, ","))
 				}
 			}
 			return nil
 		})
+	}))
+}
+
+// dockerServiceInspectAll inspects every service with a single command, because running one per service is slow on machines with many services. Results are passed to the callback in the order of names.
+func dockerServiceInspectAll(conn SshRunner, names []string, callback func([]DockerServiceInspectJson) error) SshRunner {
+	if len(names) == 0 {
+		return conn
+	}
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, shellescape.Quote(name))
+	}
+	return conn.Run(fmt.Sprint("docker service inspect ", strings.Join(args, " ")), func(res string) error {
+		var dockerInspect []DockerServiceInspectJson
+		if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
+			fmt.Fprintln(conn.Stdout(), "🚫 Could not parse docker service inspect JSON:\n", res)
+			return err
+		}
+		if len(dockerInspect) != len(names) {
+			return fmt.Errorf("docker service inspect returned %d services, expected %d", len(dockerInspect), len(names))
+		}
+		return callback(dockerInspect)
 	})
 }
@@ -0,0 +1,38 @@
+package rove
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDockerServiceInspectAll(t *testing.T) {
+	mock := &SshConnectionMock{
+		Result: `[{"Spec":{"EndpointSpec":{"Ports":[{"TargetPort":80,"PublishedPort":8080}]}}},{"Spec":{}}]`,
+	}
+	ports := make([]int, 0)
+	err := dockerServiceInspectAll(mock, []string{"files", "my app"}, func(dockerInspect []DockerServiceInspectJson) error {
+		for _, inspect := range dockerInspect {
+			ports = append(ports, len(inspect.Spec.EndpointSpec.Ports))
+		}
+		return nil
+	}).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(mock.CommandsRun, []string{"docker service inspect files 'my app'"}) {
+		t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+	}
+	if !slices.Equal(ports, []int{1, 0}) {
+		t.Errorf("'%#v' did not match expected.", ports)
+	}
+
+	mock = &SshConnectionMock{Result: `[{"Spec":{}}]`}
+	if err := dockerServiceInspectAll(mock, []string{"files", "other"}, func([]DockerServiceInspectJson) error { return nil }).Error(); err == nil {
+		t.Error("expected error when fewer services are returned than requested.")
+	}
+
+	mock = &SshConnectionMock{}
+	if err := dockerServiceInspectAll(mock, []string{}, func([]DockerServiceInspectJson) error { return nil }).Error(); err != nil || len(mock.CommandsRun) != 0 {
+		t.Errorf("expected no command for empty list, got '%#v' and '%v'.", mock.CommandsRun, err)
+	}
+}
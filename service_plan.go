@@ -0,0 +1,105 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ServicePlanCommand declares the flags of ServiceRunCommand that affect the plan, leaving out --force since nothing is deployed.
+type ServicePlanCommand struct {
+	Name    string   `arg:"" name:"name" help:"Name of service."`
+	Image   string   `arg:"" name:"image" help:"Docker image."`
+	Command []string `arg:"" name:"command" optional:"" passthrough:"" help:"Docker command."`
+
+	AllMachines         bool     `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile          string   `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Env                 []string `flag:"" name:"env" short:"e" sep:"none"`
+	Group               string   `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Init                bool     `flag:"" name:"init"`
+	Json                bool     `flag:"" name:"json" help:"Output as JSON."`
+	Local               bool     `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine             string   `flag:"" name:"machine" help:"Name of machine." default:""`
+	Mounts              []string `flag:"" name:"mount" sep:"none"`
+	Networks            []string `flag:"" name:"network" help:"Network name."`
+	Publish             []string `flag:"" name:"publish" short:"p" sep:"none"`
+	Replicas            int64    `flag:"" name:"replicas" default:"1"`
+	Secrets             []string `flag:"" name:"secret" sep:"none"`
+	UpdateDelay         string   `flag:"" name:"update-delay"`
+	UpdateFailureAction string   `flag:"" name:"update-failure-action"`
+	UpdateOrder         string   `flag:"" name:"update-order"`
+	UpdateParallelism   int64    `flag:"" name:"update-parallelism" default:"1"`
+	User                string   `flag:"" name:"user" short:"u"`
+	WorkDir             string   `flag:"" name:"workdir" short:"w"`
+}
+
+func (cmd *ServicePlanCommand) serviceRun() *ServiceRunCommand {
+	return &ServiceRunCommand{
+		Command:             cmd.Command,
+		Env:                 cmd.Env,
+		Image:               cmd.Image,
+		Init:                cmd.Init,
+		Json:                cmd.Json,
+		Mounts:              cmd.Mounts,
+		Name:                cmd.Name,
+		Networks:            cmd.Networks,
+		Publish:             cmd.Publish,
+		Replicas:            cmd.Replicas,
+		Secrets:             cmd.Secrets,
+		UpdateDelay:         cmd.UpdateDelay,
+		UpdateFailureAction: cmd.UpdateFailureAction,
+		UpdateOrder:         cmd.UpdateOrder,
+		UpdateParallelism:   cmd.UpdateParallelism,
+		User:                cmd.User,
+		WorkDir:             cmd.WorkDir,
+	}
+}
+
+func (cmd *ServicePlanCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	plan, err := cmd.serviceRun().Plan(conn)
+	if err != nil {
+		if !cmd.Json {
+			fmt.Fprintln(stdout, "🚫 Could not create deployment plan")
+		}
+		return err
+	}
+
+	if cmd.Json {
+		output := plan.Json()
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+		if plan.Status() == DiffSame {
+			return nil
+		}
+		return ErrorExit{Code: ExitChangesPending}
+	}
+
+	switch plan.Status() {
+	case DiffCreate:
+		fmt.Fprintf(stdout, "\nRove would create %s:\n\n", cmd.Name)
+	case DiffSame:
+		fmt.Fprintf(stdout, "\nRove would deploy %s without changes:\n\n", cmd.Name)
+	default:
+		fmt.Fprintf(stdout, "\nRove would update %s:\n\n", cmd.Name)
+	}
+	fmt.Fprintln(stdout, plan)
+
+	if plan.Status() == DiffSame {
+		fmt.Fprint(stdout, "\nNo changes pending.\n\n")
+		return nil
+	}
+	fmt.Fprint(stdout, "\nChanges pending.\n\n")
+	return ErrorExit{Code: ExitChangesPending}
+}
+
+func (cmd *ServicePlanCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, cmd.Do)
+	}))
+}
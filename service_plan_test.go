@@ -0,0 +1,46 @@
+package rove
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestServicePlanCommand(t *testing.T) {
+	if err := testDatabase(func() error {
+		mock := &SshConnectionMock{}
+		expectedCmd := []string{"docker service ls --format json --filter label=rove=service --filter name=files"}
+		expected := fmt.Sprint(
+			"\nRove would create files:\n\n",
+			" + service files:\n",
+			" +   image    = \"python:3.12\"\n",
+			" +   replicas = \"1\"\n",
+			"\nChanges pending.\n\n")
+
+		capture(t).
+			Run(func() error {
+				cmd := &ServicePlanCommand{
+					Image:             "python:3.12",
+					Machine:           "default",
+					Name:              "files",
+					Replicas:          1,
+					UpdateParallelism: 1,
+				}
+				err := cmd.Do(mock, nil)
+				var errExit ErrorExit
+				if !errors.As(err, &errExit) || errExit.Code != ExitChangesPending {
+					t.Errorf("'%v' did not match expected exit code %d.", err, ExitChangesPending)
+				}
+				return nil
+			}).
+			ExpectStdout(expected)
+
+		if !slices.Equal(mock.CommandsRun, expectedCmd) {
+			t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,14 +15,18 @@ import (
 type ServiceRedeployCommand struct {
 	Name string `arg:"" name:"name" help:"Name of service or task."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
-	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
-	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
-	Verbose    bool   `flag:"" name:"verbose"`
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force       bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Json        bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
+	Verbose     bool   `flag:"" name:"verbose"`
 }
 
 func (cmd *ServiceRedeployCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
 	var dockerServiceLs DockerServiceLsJson
 	commandList := fmt.Sprint("docker service ls --format json --filter label=rove=service --filter name=", shellescape.Quote(cmd.Name))
 	commandUpdate := fmt.Sprint("docker service update --force ", shellescape.Quote(cmd.Name))
@@ -29,7 +34,8 @@ func (cmd *ServiceRedeployCommand) Do(conn SshRunner, stdin io.Reader) error {
 		Name: "docker image pull",
 		Flags: []ShellFlag{
 			{
-				Check: true,
+				// Verbose pulls stream layer progress instead.
+				Check: !cmd.Verbose,
 				Name:  "quiet",
 			},
 		},
@@ -39,12 +45,12 @@ func (cmd *ServiceRedeployCommand) Do(conn SshRunner, stdin io.Reader) error {
 	err := conn.
 		Run(commandList, func(res string) error {
 			if cmd.Verbose {
-				fmt.Printf("\n[verbose] %s: %s\n", commandList, res)
+				fmt.Fprintf(stdout, "\n[verbose] %s: %s\n", commandList, res)
 			}
 			for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
 				if line != "" {
 					if err := json.Unmarshal([]byte(line), &dockerServiceLs); err != nil {
-						fmt.Println("🚫 Could not parse docker service ls JSON:\n", line)
+						fmt.Fprintln(stdout, "🚫 Could not parse docker service ls JSON:\n", line)
 						return err
 					}
 					if dockerServiceLs.Image == "" {
@@ -62,7 +68,7 @@ func (cmd *ServiceRedeployCommand) Do(conn SshRunner, stdin io.Reader) error {
 		Run(fmt.Sprint("docker service inspect --format json ", cmd.Name), func(res string) error {
 			var dockerInspect []DockerServiceInspectJson
 			if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
-				fmt.Println("🚫 Could not parse docker service inspect JSON:\n", res)
+				fmt.Fprintln(stdout, "🚫 Could not parse docker service inspect JSON:\n", res)
 				return err
 			}
 			old.Env = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Env
@@ -91,7 +97,7 @@ func (cmd *ServiceRedeployCommand) Do(conn SshRunner, stdin io.Reader) error {
 							if line != "" {
 								var dockerNetworkLs DockerNetworkLsJson
 								if err := json.Unmarshal([]byte(line), &dockerNetworkLs); err != nil {
-									fmt.Println("🚫 Could not parse docker network ls JSON:\n", line)
+									fmt.Fprintln(stdout, "🚫 Could not parse docker network ls JSON:\n", line)
 									return err
 								}
 								networksExisting = append(networksExisting, dockerNetworkLs.Name)
@@ -150,44 +156,67 @@ func (cmd *ServiceRedeployCommand) Do(conn SshRunner, stdin io.Reader) error {
 		}).
 		Error()
 	if err != nil {
-		fmt.Println("🚫 Could not create deployment plan")
+		if !cmd.Json {
+			fmt.Fprintln(stdout, "🚫 Could not create deployment plan")
+		}
 		return err
 	}
 
-	diffText, _ := old.Diff(old)
-	fmt.Printf("\nRove will redeploy %s without changes:\n\n", cmd.Name)
-	fmt.Printf("   service %s:\n", cmd.Name)
-	fmt.Println(diffText)
-	if err := confirmDeployment(cmd.Force, stdin); err != nil {
+	// With --json, the plan and prompt are written to STDERR so that STDOUT only contains the final JSON.
+	out := ternary[io.Writer](cmd.Json, conn.Stderr(), stdout)
+	if !cmd.Json || !cmd.Force {
+		diffText, _ := old.Diff(old)
+		fmt.Fprintf(out, "\nRove will redeploy %s without changes:\n\n", cmd.Name)
+		fmt.Fprintf(out, "   service %s:\n", cmd.Name)
+		fmt.Fprintln(out, diffText)
+	}
+	if err := confirmDeploymentTo(out, cmd.Force, stdin); err != nil {
 		return err
 	}
-
-	fmt.Println("\nRedeploying...")
+	if !cmd.Json {
+		fmt.Fprintln(stdout, "\nRedeploying...")
+	}
 
 	return conn.
-		Run(commandPull.String(), func(res string) error {
-			if cmd.Verbose {
-				fmt.Printf("\n[verbose] %s: %s\n", commandPull, res)
-			}
-			return nil
+		Stream(commandPull.String(), StreamHandler{
+			Stdout: func(line string) error {
+				if cmd.Verbose {
+					fmt.Fprintf(stdout, "\n[verbose] %s: %s\n", commandPull, line)
+				}
+				return nil
+			},
 		}).
 		Run(commandUpdate, func(res string) error {
 			if cmd.Verbose {
-				fmt.Printf("\n[verbose] %s: %s\n", commandUpdate, res)
+				fmt.Fprintf(stdout, "\n[verbose] %s: %s\n", commandUpdate, res)
+			}
+			if cmd.Json {
+				output := ServiceDeployJson{
+					Plan:   (&ServicePlan{Name: cmd.Name, New: old, Old: old}).Json(),
+					Result: "redeployed",
+				}
+				out, err := json.MarshalIndent(output, "", "    ")
+				if err != nil {
+					fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+					return err
+				}
+				fmt.Fprintln(stdout, string(out))
 			}
 			return nil
 		}).
 		OnError(func(err error) error {
 			if err != nil {
-				fmt.Println("🚫 Could not redeploy")
+				if !cmd.Json {
+					fmt.Fprintln(stdout, "🚫 Could not redeploy")
+				}
 			}
 			return err
 		}).
 		Error()
 }
 
-func (cmd *ServiceRedeployCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
-	})
+func (cmd *ServiceRedeployCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, cmd.Do)
+	}))
 }
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,15 +13,19 @@ import (
 type ServiceRollbackCommand struct {
 	Name string `arg:"" name:"name" help:"Name of service."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
-	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
-	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Force       bool   `flag:"" name:"force" help:"Skip confirmations."`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Json        bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
-func (cmd *ServiceRollbackCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+func (cmd *ServiceRollbackCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, func(conn SshRunner, stdin io.Reader) error {
+			stdout := conn.Stdout()
 			old := &ServiceState{
 				Command: make([]string, 0),
 				Publish: make([]string, 0),
@@ -29,7 +34,7 @@ func (cmd *ServiceRollbackCommand) Run() error {
 				Run(fmt.Sprint("docker service inspect ", cmd.Name), func(res string) error {
 					var dockerInspect []DockerServiceInspectJson
 					if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
-						fmt.Println("🚫 Could not parse docker service inspect JSON:\n", res)
+						fmt.Fprintln(stdout, "🚫 Could not parse docker service inspect JSON:\n", res)
 						return err
 					}
 					old.Command = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Args
@@ -50,32 +55,54 @@ func (cmd *ServiceRollbackCommand) Run() error {
 				}).
 				Error()
 			if err != nil {
-				fmt.Println("🚫 Could not create deployment plan")
+				if !cmd.Json {
+					fmt.Fprintln(stdout, "🚫 Could not create deployment plan")
+				}
 				return err
 			}
 
-			diffText, _ := old.Diff(old)
-			fmt.Printf("\nRove will rollback %s:\n\n", cmd.Name)
-			fmt.Printf(" ~ service %s:\n", cmd.Name)
-			fmt.Println(diffText)
-			if err := confirmDeployment(cmd.Force, stdin); err != nil {
+			// With --json, the plan and prompt are written to STDERR so that STDOUT only contains the final JSON.
+			out := ternary[io.Writer](cmd.Json, conn.Stderr(), stdout)
+			if !cmd.Json || !cmd.Force {
+				diffText, _ := old.Diff(old)
+				fmt.Fprintf(out, "\nRove will rollback %s:\n\n", cmd.Name)
+				fmt.Fprintf(out, " ~ service %s:\n", cmd.Name)
+				fmt.Fprintln(out, diffText)
+			}
+			if err := confirmDeploymentTo(out, cmd.Force, stdin); err != nil {
 				return err
 			}
-
-			fmt.Println("\nDeploying...")
+			if !cmd.Json {
+				fmt.Fprintln(stdout, "\nDeploying...")
+			}
 
 			return conn.
 				Run(fmt.Sprint("docker service update --rollback ", shellescape.Quote(cmd.Name)), func(_ string) error {
-					fmt.Printf("\nRove deleted '%s'.\n\n", cmd.Name)
+					if cmd.Json {
+						output := ServiceDeployJson{
+							Plan:   (&ServicePlan{Name: cmd.Name, New: old, Old: old}).Json(),
+							Result: "rolled back",
+						}
+						out, err := json.MarshalIndent(output, "", "    ")
+						if err != nil {
+							fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
+							return err
+						}
+						fmt.Fprintln(stdout, string(out))
+					} else {
+						fmt.Fprintf(stdout, "\nRove rolled back '%s'.\n\n", cmd.Name)
+					}
 					return nil
 				}).
 				OnError(func(err error) error {
 					if err != nil {
-						fmt.Println("🚫 Could not rollback service")
+						if !cmd.Json {
+							fmt.Fprintln(stdout, "🚫 Could not rollback service")
+						}
 					}
 					return err
 				}).
 				Error()
 		})
-	})
+	}))
 }
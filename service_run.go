@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -86,10 +87,13 @@ type ServiceRunCommand struct {
 	Image   string   `arg:"" name:"image" help:"Docker image."`
 	Command []string `arg:"" name:"command" optional:"" passthrough:"" help:"Docker command."`
 
-	ConfigFile          string   `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	AllMachines         bool     `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile          string   `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Env                 []string `flag:"" name:"env" short:"e" sep:"none"`
 	Force               bool     `flag:"" name:"force" help:"Skip confirmations."`
+	Group               string   `flag:"" name:"group" help:"Run on every machine in group in parallel."`
 	Init                bool     `flag:"" name:"init"`
+	Json                bool     `flag:"" name:"json" help:"Output as JSON."`
 	Local               bool     `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine             string   `flag:"" name:"machine" help:"Name of machine." default:""`
 	Mounts              []string `flag:"" name:"mount" sep:"none"`
@@ -106,437 +110,540 @@ type ServiceRunCommand struct {
 	WorkDir             string   `flag:"" name:"workdir" short:"w"`
 }
 
-func (cmd *ServiceRunCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
-			old := &ServiceState{}
-			new := &ServiceState{
-				Command:             cmd.Command,
-				Env:                 cmd.Env,
-				Image:               cmd.Image,
-				Init:                cmd.Init,
-				Mounts:              cmd.Mounts,
-				Networks:            cmd.Networks,
-				Publish:             cmd.Publish,
-				Replicas:            fmt.Sprint(cmd.Replicas),
-				Secrets:             cmd.Secrets,
-				UpdateDelay:         cmd.UpdateDelay,
-				UpdateOrder:         cmd.UpdateOrder,
-				UpdateFailureAction: cmd.UpdateFailureAction,
-				UpdateParallelism:   fmt.Sprint(cmd.UpdateParallelism),
-				User:                cmd.User,
-				WorkDir:             cmd.WorkDir,
-			}
+type ServicePlan struct {
+	Command     ShellCommand
+	CommandPull ShellCommand
+	Name        string
+	New         *ServiceState
+	Old         *ServiceState
+}
 
-			updateDelay := ternary(cmd.UpdateDelay == "", "0s", cmd.UpdateDelay)
-			updateFailureAction := ternary(cmd.UpdateFailureAction == "", "pause", cmd.UpdateFailureAction)
-			updateOrder := ternary(cmd.UpdateOrder == "", "stop-first", cmd.UpdateOrder)
-			if new.UpdateParallelism == "1" {
-				new.UpdateParallelism = ""
-			}
+type ServicePlanJson struct {
+	Action string          `json:"action"`
+	Fields []DiffFieldJson `json:"fields"`
+	Name   string          `json:"name"`
+}
 
-			command := ShellCommand{
-				Name: "docker service create",
-				Flags: []ShellFlag{
-					{
-						Check: cmd.Init,
-						Name:  "init",
-					},
-					{
-						Check: true,
-						Name:  "replicas",
-						Value: fmt.Sprintf("%d", cmd.Replicas),
-					},
-					{
-						Check: true,
-						Name:  "update-delay",
-						Value: updateDelay,
-					},
-					{
-						Check: true,
-						Name:  "update-failure-action",
-						Value: updateFailureAction,
-					},
-					{
-						Check: true,
-						Name:  "update-order",
-						Value: updateOrder,
-					},
-					{
-						Check: true,
-						Name:  "update-parallelism",
-						Value: fmt.Sprint(cmd.UpdateParallelism),
-					},
-					{
-						AllowEmpty: true,
-						Check:      true,
-						Name:       "user",
-						Value:      cmd.User,
-					},
-					{
-						AllowEmpty: true,
-						Check:      true,
-						Name:       "workdir",
-						Value:      cmd.WorkDir,
-					},
-				},
-			}
+type ServiceDeployJson struct {
+	Plan   ServicePlanJson `json:"plan"`
+	Result string          `json:"result"`
+}
 
-			commandPull := ShellCommand{
-				Name: "docker image pull",
-				Args: []ShellArg{
-					{
-						Check: true,
-						Value: shellescape.Quote(cmd.Image),
-					},
-				},
-				Flags: []ShellFlag{
-					{
-						Check: true,
-						Name:  "quiet",
-					},
+func servicePlanJson(name string, action string, old *ServiceState, new *ServiceState) ServicePlanJson {
+	lines, _ := new.DiffLines(old)
+	return ServicePlanJson{
+		Action: action,
+		Fields: diffFieldsJson(lines),
+		Name:   name,
+	}
+}
+
+func (plan *ServicePlan) Action() string {
+	if plan.Create() {
+		return "create"
+	}
+	if plan.Status() == DiffSame {
+		return "noop"
+	}
+	return "update"
+}
+
+func (plan *ServicePlan) Create() bool {
+	return plan.Command.Name == "docker service create"
+}
+
+func (plan *ServicePlan) Status() DiffStatus {
+	if plan.Create() {
+		return DiffCreate
+	}
+	_, status := plan.New.Diff(plan.Old)
+	return status
+}
+
+func (plan *ServicePlan) Json() ServicePlanJson {
+	return servicePlanJson(plan.Name, plan.Action(), plan.Old, plan.New)
+}
+
+func (plan *ServicePlan) String() string {
+	diffText, _ := plan.New.Diff(plan.Old)
+	symbol := "~"
+	if plan.Create() {
+		symbol = "+"
+	} else if plan.Status() == DiffSame {
+		symbol = " "
+	}
+	return fmt.Sprintf(" %s service %s:\n%s", symbol, plan.Name, diffText)
+}
+
+func (cmd *ServiceRunCommand) Plan(conn SshRunner) (*ServicePlan, error) {
+	stdout := conn.Stdout()
+	old := &ServiceState{}
+	new := &ServiceState{
+		Command:             cmd.Command,
+		Env:                 cmd.Env,
+		Image:               cmd.Image,
+		Init:                cmd.Init,
+		Mounts:              cmd.Mounts,
+		Networks:            cmd.Networks,
+		Publish:             cmd.Publish,
+		Replicas:            fmt.Sprint(cmd.Replicas),
+		Secrets:             cmd.Secrets,
+		UpdateDelay:         cmd.UpdateDelay,
+		UpdateOrder:         cmd.UpdateOrder,
+		UpdateFailureAction: cmd.UpdateFailureAction,
+		UpdateParallelism:   fmt.Sprint(cmd.UpdateParallelism),
+		User:                cmd.User,
+		WorkDir:             cmd.WorkDir,
+	}
+
+	updateDelay := ternary(cmd.UpdateDelay == "", "0s", cmd.UpdateDelay)
+	updateFailureAction := ternary(cmd.UpdateFailureAction == "", "pause", cmd.UpdateFailureAction)
+	updateOrder := ternary(cmd.UpdateOrder == "", "stop-first", cmd.UpdateOrder)
+	if new.UpdateParallelism == "1" {
+		new.UpdateParallelism = ""
+	}
+
+	command := ShellCommand{
+		Name: "docker service create",
+		Flags: []ShellFlag{
+			{
+				Check: cmd.Init,
+				Name:  "init",
+			},
+			{
+				Check: true,
+				Name:  "replicas",
+				Value: fmt.Sprintf("%d", cmd.Replicas),
+			},
+			{
+				Check: true,
+				Name:  "update-delay",
+				Value: updateDelay,
+			},
+			{
+				Check: true,
+				Name:  "update-failure-action",
+				Value: updateFailureAction,
+			},
+			{
+				Check: true,
+				Name:  "update-order",
+				Value: updateOrder,
+			},
+			{
+				Check: true,
+				Name:  "update-parallelism",
+				Value: fmt.Sprint(cmd.UpdateParallelism),
+			},
+			{
+				AllowEmpty: true,
+				Check:      true,
+				Name:       "user",
+				Value:      cmd.User,
+			},
+			{
+				AllowEmpty: true,
+				Check:      true,
+				Name:       "workdir",
+				Value:      cmd.WorkDir,
+			},
+		},
+	}
+
+	commandPull := ShellCommand{
+		Name: "docker image pull",
+		Args: []ShellArg{
+			{
+				Check: true,
+				Value: shellescape.Quote(cmd.Image),
+			},
+		},
+		Flags: []ShellFlag{
+			{
+				// Verbose pulls stream layer progress instead.
+				Check: !cmd.Verbose,
+				Name:  "quiet",
+			},
+		},
+	}
+
+	err := conn.
+		Run(fmt.Sprint("docker service ls --format json --filter label=rove=service --filter name=", cmd.Name), func(res string) error {
+			if lines := strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n"); len(lines) > 1 {
+				return nil
+			}
+			command.Flags = append(command.Flags, ShellFlag{
+				Check: true,
+				Name:  "label",
+				Value: "rove=service",
+			})
+			command.Flags = append(command.Flags, ShellFlag{
+				Check: true,
+				Name:  "name",
+				Value: cmd.Name,
+			})
+			for _, env := range cmd.Env {
+				command.Flags = append(command.Flags, ShellFlag{
+					Check: env != "",
+					Name:  "env",
+					Value: env,
+				})
+			}
+			for _, mount := range cmd.Mounts {
+				command.Flags = append(command.Flags, ShellFlag{
+					Check: mount != "",
+					Name:  "mount",
+					Value: mount,
+				})
+			}
+			for _, network := range cmd.Networks {
+				command.Flags = append(command.Flags, ShellFlag{
+					Check: network != "",
+					Name:  "network",
+					Value: network,
+				})
+			}
+			for _, p := range cmd.Publish {
+				command.Flags = append(command.Flags, ShellFlag{
+					Check: p != "",
+					Name:  "publish",
+					Value: p,
+				})
+			}
+			for _, secret := range cmd.Secrets {
+				command.Flags = append(command.Flags, ShellFlag{
+					Check: secret != "",
+					Name:  "secret",
+					Value: secret,
+				})
+			}
+			command.Args = append(command.Args,
+				ShellArg{
+					Check: true,
+					Value: shellescape.Quote(cmd.Image),
 				},
+			)
+			for _, arg := range cmd.Command {
+				command.Args = append(command.Args, ShellArg{
+					Check: true,
+					Value: shellescape.Quote(arg),
+				})
+			}
+			return ErrorSkip{}
+		}).
+		Run(fmt.Sprint("docker service inspect ", cmd.Name), func(res string) error {
+			var dockerInspect []DockerServiceInspectJson
+			if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
+				fmt.Fprintln(stdout, "🚫 Could not parse docker service inspect JSON:\n", res)
+				return err
 			}
 
-			err := conn.
-				Run(fmt.Sprint("docker service ls --format json --filter label=rove=service --filter name=", cmd.Name), func(res string) error {
-					if lines := strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n"); len(lines) > 1 {
-						return nil
-					}
+			// Environment variables
+			old.Env = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Env
+			for _, env := range old.Env {
+				if !slices.Contains(cmd.Env, env) {
+					envName := strings.Split(env, "=")[0]
 					command.Flags = append(command.Flags, ShellFlag{
-						Check: true,
-						Name:  "label",
-						Value: "rove=service",
+						Check: envName != "",
+						Name:  "env-rm",
+						Value: envName,
 					})
+				}
+			}
+			for _, env := range new.Env {
+				if !slices.Contains(old.Env, env) {
 					command.Flags = append(command.Flags, ShellFlag{
-						Check: true,
-						Name:  "name",
-						Value: cmd.Name,
+						Check: env != "",
+						Name:  "env-add",
+						Value: env,
 					})
-					for _, env := range cmd.Env {
-						command.Flags = append(command.Flags, ShellFlag{
-							Check: env != "",
-							Name:  "env",
-							Value: env,
-						})
-					}
-					for _, mount := range cmd.Mounts {
-						command.Flags = append(command.Flags, ShellFlag{
-							Check: mount != "",
-							Name:  "mount",
-							Value: mount,
-						})
-					}
-					for _, network := range cmd.Networks {
-						command.Flags = append(command.Flags, ShellFlag{
-							Check: network != "",
-							Name:  "network",
-							Value: network,
-						})
-					}
-					for _, p := range cmd.Publish {
-						command.Flags = append(command.Flags, ShellFlag{
-							Check: p != "",
-							Name:  "publish",
-							Value: p,
-						})
-					}
-					for _, secret := range cmd.Secrets {
-						command.Flags = append(command.Flags, ShellFlag{
-							Check: secret != "",
-							Name:  "secret",
-							Value: secret,
-						})
-					}
-					command.Args = append(command.Args,
-						ShellArg{
-							Check: true,
-							Value: shellescape.Quote(cmd.Image),
-						},
-					)
-					for _, arg := range cmd.Command {
-						command.Args = append(command.Args, ShellArg{
-							Check: true,
-							Value: shellescape.Quote(arg),
-						})
-					}
-					return ErrorSkip{}
-				}).
-				Run(fmt.Sprint("docker service inspect ", cmd.Name), func(res string) error {
-					var dockerInspect []DockerServiceInspectJson
-					if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
-						fmt.Println("🚫 Could not parse docker service inspect JSON:\n", res)
-						return err
-					}
+				}
+			}
 
-					// Environment variables
-					old.Env = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Env
-					for _, env := range old.Env {
-						if !slices.Contains(cmd.Env, env) {
-							envName := strings.Split(env, "=")[0]
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: envName != "",
-								Name:  "env-rm",
-								Value: envName,
-							})
-						}
-					}
-					for _, env := range new.Env {
-						if !slices.Contains(old.Env, env) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: env != "",
-								Name:  "env-add",
-								Value: env,
-							})
-						}
-					}
+			// Mounts
+			oldMountStrings := make(map[string]string, 0)
+			oldMountTargets := make([]string, 0)
+			newMountTargets := make([]string, 0)
 
-					// Mounts
-					oldMountStrings := make(map[string]string, 0)
-					oldMountTargets := make([]string, 0)
-					newMountTargets := make([]string, 0)
+			for _, mount := range dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Mounts {
+				oldMountTargets = append(oldMountTargets, mount.Target)
+				oldMountStrings[mount.Target] = formatStateMount(mount)
+				old.Mounts = append(old.Mounts, oldMountStrings[mount.Target])
+			}
 
-					for _, mount := range dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Mounts {
-						oldMountTargets = append(oldMountTargets, mount.Target)
-						oldMountStrings[mount.Target] = formatStateMount(mount)
-						old.Mounts = append(old.Mounts, oldMountStrings[mount.Target])
+			new.Mounts = make([]string, 0)
+			for _, mount := range cmd.Mounts {
+				// Find new mount targets
+				newMountTarget := ""
+				for _, mountOption := range strings.Split(mount, ",") {
+					mountOptionParts := strings.SplitN(mountOption, "=", 2)
+					if len(mountOptionParts) > 1 && (mountOptionParts[0] == "destination" || mountOptionParts[0] == "dst" || mountOptionParts[0] == "target") {
+						newMountTarget = mountOptionParts[1]
 					}
+				}
+				if newMountTarget == "" {
+					return fmt.Errorf("cannot add mount without target: '%s'. Documentation: https://docs.docker.com/reference/cli/docker/service/create/#mount", mount)
+				}
 
-					new.Mounts = make([]string, 0)
-					for _, mount := range cmd.Mounts {
-						// Find new mount targets
-						newMountTarget := ""
-						for _, mountOption := range strings.Split(mount, ",") {
-							mountOptionParts := strings.SplitN(mountOption, "=", 2)
-							if len(mountOptionParts) > 1 && (mountOptionParts[0] == "destination" || mountOptionParts[0] == "dst" || mountOptionParts[0] == "target") {
-								newMountTarget = mountOptionParts[1]
-							}
-						}
-						if newMountTarget == "" {
-							return fmt.Errorf("cannot add mount without target: '%s'. Documentation: https://docs.docker.com/reference/cli/docker/service/create/#mount", mount)
-						}
+				newMountTargets = append(newMountTargets, newMountTarget)
 
-						newMountTargets = append(newMountTargets, newMountTarget)
-
-						if slices.Contains(oldMountTargets, newMountTarget) {
-							// Copy old mount string to new state, because we don't auto-delete mutated mounts
-							new.Mounts = append(new.Mounts, oldMountStrings[newMountTarget])
-						} else {
-							// Add new mounts
-							new.Mounts = append(new.Mounts, mount)
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: true,
-								Name:  "mount-add",
-								Value: mount,
-							})
-						}
-					}
-					for _, oldMountTarget := range oldMountTargets {
-						// Remove mounts
-						// TODO: Flag for forcing removal of mounts.
-						if !slices.Contains(newMountTargets, oldMountTarget) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: true,
-								Name:  "mount-rm",
-								Value: oldMountTarget,
-							})
-						}
-					}
+				if slices.Contains(oldMountTargets, newMountTarget) {
+					// Copy old mount string to new state, because we don't auto-delete mutated mounts
+					new.Mounts = append(new.Mounts, oldMountStrings[newMountTarget])
+				} else {
+					// Add new mounts
+					new.Mounts = append(new.Mounts, mount)
+					command.Flags = append(command.Flags, ShellFlag{
+						Check: true,
+						Name:  "mount-add",
+						Value: mount,
+					})
+				}
+			}
+			for _, oldMountTarget := range oldMountTargets {
+				// Remove mounts
+				// TODO: Flag for forcing removal of mounts.
+				if !slices.Contains(newMountTargets, oldMountTarget) {
+					command.Flags = append(command.Flags, ShellFlag{
+						Check: true,
+						Name:  "mount-rm",
+						Value: oldMountTarget,
+					})
+				}
+			}
 
-					// Networks
-					networksExisting := make([]string, 0)
-					networksExistingIds := make([]string, 0)
-					for _, network := range dockerInspect[0].Spec.TaskTemplate.Networks {
-						networksExistingIds = append(networksExistingIds, network.Target)
-					}
-					if len(networksExistingIds) > 0 {
-						commandNetworks := ShellCommand{
-							Name: "docker network ls --format json --no-trunc",
-						}
-						for _, networkId := range networksExistingIds {
-							commandNetworks.Flags = append(commandNetworks.Flags, ShellFlag{
-								Check: networkId != "",
-								Name:  "filter",
-								Value: shellescape.Quote("id=" + networkId),
-							})
-						}
-						errNetwork := conn.
-							Run(commandNetworks.String(), func(resNetworks string) error {
-								for _, line := range strings.Split(strings.ReplaceAll(resNetworks, "\r\n", "\n"), "\n") {
-									if line != "" {
-										var dockerNetworkLs DockerNetworkLsJson
-										if err := json.Unmarshal([]byte(line), &dockerNetworkLs); err != nil {
-											fmt.Println("🚫 Could not parse docker network ls JSON:\n", line)
-											return err
-										}
-										networksExisting = append(networksExisting, dockerNetworkLs.Name)
-									}
+			// Networks
+			networksExisting := make([]string, 0)
+			networksExistingIds := make([]string, 0)
+			for _, network := range dockerInspect[0].Spec.TaskTemplate.Networks {
+				networksExistingIds = append(networksExistingIds, network.Target)
+			}
+			if len(networksExistingIds) > 0 {
+				commandNetworks := ShellCommand{
+					Name: "docker network ls --format json --no-trunc",
+				}
+				for _, networkId := range networksExistingIds {
+					commandNetworks.Flags = append(commandNetworks.Flags, ShellFlag{
+						Check: networkId != "",
+						Name:  "filter",
+						Value: shellescape.Quote("id=" + networkId),
+					})
+				}
+				errNetwork := conn.
+					Run(commandNetworks.String(), func(resNetworks string) error {
+						for _, line := range strings.Split(strings.ReplaceAll(resNetworks, "\r\n", "\n"), "\n") {
+							if line != "" {
+								var dockerNetworkLs DockerNetworkLsJson
+								if err := json.Unmarshal([]byte(line), &dockerNetworkLs); err != nil {
+									fmt.Fprintln(stdout, "🚫 Could not parse docker network ls JSON:\n", line)
+									return err
 								}
-								return nil
-							}).
-							Error()
-						if errNetwork != nil {
-							return errNetwork
-						}
-						for _, network := range networksExisting {
-							if !slices.Contains(cmd.Networks, network) {
-								command.Flags = append(command.Flags, ShellFlag{
-									Check: network != "",
-									Name:  "network-rm",
-									Value: network,
-								})
+								networksExisting = append(networksExisting, dockerNetworkLs.Name)
 							}
 						}
+						return nil
+					}).
+					Error()
+				if errNetwork != nil {
+					return errNetwork
+				}
+				for _, network := range networksExisting {
+					if !slices.Contains(cmd.Networks, network) {
+						command.Flags = append(command.Flags, ShellFlag{
+							Check: network != "",
+							Name:  "network-rm",
+							Value: network,
+						})
 					}
-					for _, network := range cmd.Networks {
-						if !slices.Contains(networksExisting, network) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: network != "",
-								Name:  "network-add",
-								Value: network,
-							})
-						}
-					}
-
-					// Ports
-					portsExisting := make([]string, 0)
-					for _, entry := range dockerInspect[0].Spec.EndpointSpec.Ports {
-						port := fmt.Sprintf("%d:%d", entry.TargetPort, entry.PublishedPort)
-						if entry.Protocol != "tcp" {
-							port += fmt.Sprint("/", entry.Protocol)
-						}
-						portsExisting = append(portsExisting, port)
-						if !slices.Contains(cmd.Publish, port) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: true,
-								Name:  "publish-rm",
-								Value: port,
-							})
-						}
-					}
-					for _, port := range cmd.Publish {
-						if !slices.Contains(portsExisting, port) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: true,
-								Name:  "publish-add",
-								Value: port,
-							})
-						}
-					}
-
-					// Secrets
-					secretsExisting := make([]string, 0)
-					for _, secret := range dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Secrets {
-						secretsExisting = append(secretsExisting, secret.SecretName)
-						if !slices.Contains(cmd.Secrets, secret.SecretName) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: secret.SecretName != "",
-								Name:  "secret-rm",
-								Value: secret.SecretName,
-							})
-						}
-					}
-					for _, secret := range cmd.Secrets {
-						if !slices.Contains(secretsExisting, secret) {
-							command.Flags = append(command.Flags, ShellFlag{
-								Check: secret != "",
-								Name:  "secret-add",
-								Value: secret,
-							})
-						}
-					}
-
-					old.Command = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Args
-					old.Image = strings.Split(dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Image, "@")[0]
-					old.Init = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Init
-					if old.Init {
-						new.Init = true
-					}
-					old.Networks = networksExisting
-					old.Publish = portsExisting
-					old.Replicas = fmt.Sprint(dockerInspect[0].Spec.Mode.Replicated.Replicas)
-					old.Secrets = secretsExisting
-					if dockerInspect[0].Spec.UpdateConfig.Delay != 0 {
-						delayNs, _ := time.ParseDuration(fmt.Sprint(dockerInspect[0].Spec.UpdateConfig.Delay, "ns"))
-						old.UpdateDelay, _ = strings.CutSuffix(delayNs.String(), "m0s")
-					}
-					old.UpdateFailureAction = dockerInspect[0].Spec.UpdateConfig.FailureAction
-					if old.UpdateFailureAction == "pause" {
-						old.UpdateFailureAction = ""
-					}
-					old.UpdateOrder = dockerInspect[0].Spec.UpdateConfig.Order
-					if old.UpdateOrder == "stop-first" {
-						old.UpdateOrder = ""
-					}
-					old.UpdateParallelism = fmt.Sprint(dockerInspect[0].Spec.UpdateConfig.Parallelism)
-					if old.UpdateParallelism == "1" {
-						old.UpdateParallelism = ""
-					}
-					old.User = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.User
-					old.WorkDir = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Dir
-
-					command.Name = "docker service update"
+				}
+			}
+			for _, network := range cmd.Networks {
+				if !slices.Contains(networksExisting, network) {
 					command.Flags = append(command.Flags, ShellFlag{
-						Check: len(cmd.Command) > 0,
-						Name:  "args",
-						Value: shellescape.QuoteCommand(cmd.Command),
+						Check: network != "",
+						Name:  "network-add",
+						Value: network,
 					})
+				}
+			}
+
+			// Ports
+			portsExisting := make([]string, 0)
+			for _, entry := range dockerInspect[0].Spec.EndpointSpec.Ports {
+				port := fmt.Sprintf("%d:%d", entry.TargetPort, entry.PublishedPort)
+				if entry.Protocol != "tcp" {
+					port += fmt.Sprint("/", entry.Protocol)
+				}
+				portsExisting = append(portsExisting, port)
+				if !slices.Contains(cmd.Publish, port) {
 					command.Flags = append(command.Flags, ShellFlag{
 						Check: true,
-						Name:  "image",
-						Value: cmd.Image,
+						Name:  "publish-rm",
+						Value: port,
 					})
-					command.Args = append(command.Args, ShellArg{
+				}
+			}
+			for _, port := range cmd.Publish {
+				if !slices.Contains(portsExisting, port) {
+					command.Flags = append(command.Flags, ShellFlag{
 						Check: true,
-						Value: shellescape.Quote(cmd.Name),
+						Name:  "publish-add",
+						Value: port,
 					})
-					return nil
-				}).
-				OnError(SkipReset).
-				Error()
-			if err != nil {
-				fmt.Println("🚫 Could not create deployment plan")
-				return err
+				}
 			}
 
-			diffText, diffStatus := new.Diff(old)
-			if command.Name == "docker service create" {
-				fmt.Printf("\nRove will create %s:\n\n", cmd.Name)
-				fmt.Printf(" + service %s:\n", cmd.Name)
-			} else {
-				if diffStatus == DiffSame {
-					fmt.Printf("\nRove will deploy %s without changes:\n\n", cmd.Name)
-					fmt.Printf("   service %s:\n", cmd.Name)
-				} else {
-					fmt.Printf("\nRove will update %s:\n\n", cmd.Name)
-					fmt.Printf(" ~ service %s:\n", cmd.Name)
+			// Secrets
+			secretsExisting := make([]string, 0)
+			for _, secret := range dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Secrets {
+				secretsExisting = append(secretsExisting, secret.SecretName)
+				if !slices.Contains(cmd.Secrets, secret.SecretName) {
+					command.Flags = append(command.Flags, ShellFlag{
+						Check: secret.SecretName != "",
+						Name:  "secret-rm",
+						Value: secret.SecretName,
+					})
 				}
 			}
-			fmt.Println(diffText)
-			if err := confirmDeployment(cmd.Force, stdin); err != nil {
-				return err
+			for _, secret := range cmd.Secrets {
+				if !slices.Contains(secretsExisting, secret) {
+					command.Flags = append(command.Flags, ShellFlag{
+						Check: secret != "",
+						Name:  "secret-add",
+						Value: secret,
+					})
+				}
 			}
 
-			fmt.Println("\nDeploying...")
+			old.Command = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Args
+			old.Image = strings.Split(dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Image, "@")[0]
+			old.Init = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Init
+			if old.Init {
+				new.Init = true
+			}
+			old.Networks = networksExisting
+			old.Publish = portsExisting
+			old.Replicas = fmt.Sprint(dockerInspect[0].Spec.Mode.Replicated.Replicas)
+			old.Secrets = secretsExisting
+			if dockerInspect[0].Spec.UpdateConfig.Delay != 0 {
+				delayNs, _ := time.ParseDuration(fmt.Sprint(dockerInspect[0].Spec.UpdateConfig.Delay, "ns"))
+				old.UpdateDelay, _ = strings.CutSuffix(delayNs.String(), "m0s")
+			}
+			old.UpdateFailureAction = dockerInspect[0].Spec.UpdateConfig.FailureAction
+			if old.UpdateFailureAction == "pause" {
+				old.UpdateFailureAction = ""
+			}
+			old.UpdateOrder = dockerInspect[0].Spec.UpdateConfig.Order
+			if old.UpdateOrder == "stop-first" {
+				old.UpdateOrder = ""
+			}
+			old.UpdateParallelism = fmt.Sprint(dockerInspect[0].Spec.UpdateConfig.Parallelism)
+			if old.UpdateParallelism == "1" {
+				old.UpdateParallelism = ""
+			}
+			old.User = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.User
+			old.WorkDir = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Dir
 
-			return conn.
-				Run(commandPull.String(), func(res string) error {
-					if cmd.Verbose {
-						fmt.Printf("\n[verbose] %s: %s", commandPull.String(), res)
-					}
-					return nil
-				}).
-				Run(command.String(), func(res string) error {
-					fmt.Printf("\nRove deployed '%s'.\n\n", cmd.Name)
-					return nil
-				}).
-				OnError(func(err error) error {
-					if err != nil {
-						fmt.Println("🚫 Could not deploy service")
-					}
+			command.Name = "docker service update"
+			command.Flags = append(command.Flags, ShellFlag{
+				Check: len(cmd.Command) > 0,
+				Name:  "args",
+				Value: shellescape.QuoteCommand(cmd.Command),
+			})
+			command.Flags = append(command.Flags, ShellFlag{
+				Check: true,
+				Name:  "image",
+				Value: cmd.Image,
+			})
+			command.Args = append(command.Args, ShellArg{
+				Check: true,
+				Value: shellescape.Quote(cmd.Name),
+			})
+			return nil
+		}).
+		OnError(SkipReset).
+		Error()
+	if err != nil {
+		return nil, err
+	}
+	return &ServicePlan{
+		Command:     command,
+		CommandPull: commandPull,
+		Name:        cmd.Name,
+		New:         new,
+		Old:         old,
+	}, nil
+}
+
+func (cmd *ServiceRunCommand) Do(conn SshRunner, stdin io.Reader) error {
+	stdout := conn.Stdout()
+	plan, err := cmd.Plan(conn)
+	if err != nil {
+		if !cmd.Json {
+			fmt.Fprintln(stdout, "🚫 Could not create deployment plan")
+		}
+		return err
+	}
+
+	// With --json, the plan and prompt are written to STDERR so that STDOUT only contains the final JSON. The plan is skipped there with --force, since nothing needs approving.
+	out := ternary[io.Writer](cmd.Json, conn.Stderr(), stdout)
+	if !cmd.Json || !cmd.Force {
+		switch plan.Status() {
+		case DiffCreate:
+			fmt.Fprintf(out, "\nRove will create %s:\n\n", cmd.Name)
+		case DiffSame:
+			fmt.Fprintf(out, "\nRove will deploy %s without changes:\n\n", cmd.Name)
+		default:
+			fmt.Fprintf(out, "\nRove will update %s:\n\n", cmd.Name)
+		}
+		fmt.Fprintln(out, plan)
+	}
+	if err := confirmDeploymentTo(out, cmd.Force, stdin); err != nil {
+		return err
+	}
+	if !cmd.Json {
+		fmt.Fprintln(stdout, "\nDeploying...")
+	}
+
+	return conn.
+		Stream(plan.CommandPull.String(), StreamHandler{
+			Stdout: func(line string) error {
+				if cmd.Verbose {
+					fmt.Fprintf(stdout, "\n[verbose] %s: %s", plan.CommandPull.String(), line)
+				}
+				return nil
+			},
+		}).
+		Run(plan.Command.String(), func(res string) error {
+			if cmd.Json {
+				output := ServiceDeployJson{
+					Plan:   plan.Json(),
+					Result: "deployed",
+				}
+				out, err := json.MarshalIndent(output, "", "    ")
+				if err != nil {
+					fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
 					return err
-				}).
-				Error()
-		})
-	})
+				}
+				fmt.Fprintln(stdout, string(out))
+			} else {
+				fmt.Fprintf(stdout, "\nRove deployed '%s'.\n\n", cmd.Name)
+			}
+			return nil
+		}).
+		OnError(func(err error) error {
+			if err != nil {
+				if !cmd.Json {
+					fmt.Fprintln(stdout, "🚫 Could not deploy service")
+				}
+			}
+			return err
+		}).
+		Error()
+}
+
+func (cmd *ServiceRunCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, cmd.Do)
+	}))
 }
@@ -0,0 +1,113 @@
+package rove
+
+import (
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestServiceRunCommandJson(t *testing.T) {
+	if err := testDatabase(func() error {
+		mock := &SshConnectionMock{}
+		expectedCmd := []string{
+			"docker service ls --format json --filter label=rove=service --filter name=files",
+			"docker image pull --quiet python:3.12",
+			"docker service create --replicas 1 --update-delay 0s --update-failure-action pause --update-order stop-first --update-parallelism 1 --user '' --workdir '' --label rove=service --name files python:3.12",
+		}
+		expected := `{
+    "plan": {
+        "action": "create",
+        "fields": [
+            {
+                "name": "image",
+                "new": "python:3.12",
+                "status": "create"
+            },
+            {
+                "name": "replicas",
+                "new": "1",
+                "status": "create"
+            }
+        ],
+        "name": "files"
+    },
+    "result": "deployed"
+}` + "\n"
+
+		capture(t).
+			Run(func() error {
+				cmd := &ServiceRunCommand{
+					Force:             true,
+					Image:             "python:3.12",
+					Json:              true,
+					Machine:           "default",
+					Name:              "files",
+					Replicas:          1,
+					UpdateParallelism: 1,
+				}
+				return cmd.Do(mock, nil)
+			}).
+			ExpectStdout(expected)
+
+		if !slices.Equal(mock.CommandsRun, expectedCmd) {
+			t.Errorf("'%#v' did not match expected.", mock.CommandsRun)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceRunCommandJsonPrompt(t *testing.T) {
+	if err := testDatabase(func() error {
+		savedStderr := os.Stderr
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		os.Stderr = w
+		defer func() { os.Stderr = savedStderr }()
+
+		capture(t).Run(func() error {
+			cmd := &ServiceRunCommand{
+				Image:             "python:3.12",
+				Json:              true,
+				Name:              "files",
+				Replicas:          1,
+				UpdateParallelism: 1,
+			}
+			return cmd.Do(&SshConnectionMock{}, strings.NewReader("yes\n"))
+		})
+		w.Close()
+		stderr, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		plan := strings.Index(string(stderr), "Rove will create files:")
+		prompt := strings.Index(string(stderr), "Do you want Rove to run this deployment?")
+		if plan < 0 || prompt < plan {
+			t.Errorf("'%s' STDERR did not show the plan before the prompt.", stderr)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffFieldsJson(t *testing.T) {
+	old := &ServiceState{Env: []string{"A=1"}, Image: "python:3.11", Replicas: "1"}
+	new := &ServiceState{Image: "python:3.12", Publish: []string{"80:80"}, Replicas: "1"}
+	lines, _ := new.DiffLines(old)
+	fields := diffFieldsJson(lines)
+	expected := []DiffFieldJson{
+		{Name: "env", Old: []byte(`["A=1"]`), Status: "delete"},
+		{Name: "image", New: []byte(`"python:3.12"`), Old: []byte(`"python:3.11"`), Status: "update"},
+		{Name: "publish", New: []byte(`["80:80"]`), Status: "create"},
+		{Name: "replicas", New: []byte(`"1"`), Old: []byte(`"1"`), Status: "same"},
+	}
+	if mustMarshal(fields) != mustMarshal(expected) {
+		t.Errorf("'%s' did not match expected '%s'.", mustMarshal(fields), mustMarshal(expected))
+	}
+}
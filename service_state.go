@@ -29,9 +29,22 @@ type ServiceState struct {
 }
 
 func (new *ServiceState) Diff(old *ServiceState) (string, DiffStatus) {
-	lines := make([]DiffLine, 0)
+	lines, status := new.DiffLines(old)
 	maxLeft := 0
-	res := make([]string, len(lines))
+	res := make([]string, 0)
+	for _, line := range lines {
+		if len(line.Left) > maxLeft {
+			maxLeft = len(line.Left)
+		}
+	}
+	for _, line := range lines {
+		res = append(res, line.StringPadded(maxLeft))
+	}
+	return strings.Join(res, "\n"), status
+}
+
+func (new *ServiceState) DiffLines(old *ServiceState) ([]DiffLine, DiffStatus) {
+	lines := make([]DiffLine, 0)
 	status := DiffSame
 
 	lines, status = diffSlices(lines, status, "command", old.Command, new.Command)
@@ -49,16 +62,7 @@ func (new *ServiceState) Diff(old *ServiceState) (string, DiffStatus) {
 	lines, status = diffString(lines, status, "update-parallelism", old.UpdateParallelism, new.UpdateParallelism)
 	lines, status = diffString(lines, status, "user", old.User, new.User)
 	lines, status = diffString(lines, status, "workdir", old.WorkDir, new.WorkDir)
-
-	for _, line := range lines {
-		if len(line.Left) > maxLeft {
-			maxLeft = len(line.Left)
-		}
-	}
-	for _, line := range lines {
-		res = append(res, line.StringPadded(maxLeft))
-	}
-	return strings.Join(res, "\n"), status
+	return lines, status
 }
 
 func formatStateMapKebab(state map[string]string) string {
@@ -4,21 +4,31 @@ import (
 	"bufio"
 	"bytes"
 	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/evantbyrne/trance"
 	"github.com/kballard/go-shellquote"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/term"
 )
 
 type LocalRunner struct {
 	Err error
+
+	ctx    context.Context
+	stderr io.Writer
 }
 
 func (conn *LocalRunner) Error() error {
@@ -32,81 +42,393 @@ func (conn *LocalRunner) OnError(callback func(error) error) SshRunner {
 	return conn
 }
 
+func (conn *LocalRunner) Stderr() io.Writer {
+	if conn.stderr != nil {
+		return conn.stderr
+	}
+	return os.Stderr
+}
+
+func (conn *LocalRunner) Stdout() io.Writer {
+	return os.Stdout
+}
+
+func (conn *LocalRunner) setStderr(stderr io.Writer) {
+	conn.stderr = stderr
+}
+
 func (conn *LocalRunner) Run(command string, callback func(string) error) SshRunner {
+	return conn.RunContext(runnerContext(conn.ctx), command, callback)
+}
+
+func (conn *LocalRunner) RunContext(ctx context.Context, command string, callback func(string) error) SshRunner {
 	if conn.Err != nil {
 		return conn
 	}
+	var bufferStderr bytes.Buffer
 	var bufferStdout bytes.Buffer
 	words, err := shellquote.Split(command)
 	if err != nil {
 		conn.Err = fmt.Errorf("failed to shell split command '%s': %v", command, err)
 		return conn
 	}
-	cmd := exec.Command(words[0], words[1:]...)
-	cmd.Stderr = os.Stderr
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	cmd.Stderr = &bufferStderr
 	cmd.Stdout = &bufferStdout
 	if err := cmd.Run(); err != nil {
-		conn.Err = fmt.Errorf("failed to run command '%s': %v", command, err)
+		conn.Err = commandError(ctx, command, err, bufferStderr.String())
 		return conn
 	}
+	conn.Stderr().Write(bufferStderr.Bytes())
 	conn.Err = callback(bufferStdout.String())
 	return conn
 }
 
-func SshConnect(address string, user string, key []byte, callback func(conn SshRunner, stdin io.Reader) error) error {
+const SshPassphraseEnv = "ROVE_SSH_PASSPHRASE"
+
+// sshDecrypted caches encrypted private keys by path once their passphrase has been entered, for the life of the process.
+var sshDecrypted = map[string]ssh.Signer{}
+
+// sshDecryptedLock keeps passphrase prompts for machines connected to in parallel from interleaving.
+var sshDecryptedLock sync.Mutex
+
+// sshAuth authenticates with the private key file when one is given, and otherwise with the SSH agent at SSH_AUTH_SOCK. The returned function releases the agent connection.
+func sshAuth(keyPath string) (ssh.AuthMethod, func(), error) {
+	if keyPath == "" {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, nil, errors.New("🚫 No private key file configured and no SSH agent available. Either provide a private key file or set SSH_AUTH_SOCK")
+		}
+		agentConn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to connect to SSH agent: %v", err)
+		}
+		return ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers), func() { agentConn.Close() }, nil
+	}
+
+	key, err := sshKeyRead(keyPath)
+	if err != nil {
+		return nil, nil, ErrorSsh{Address: keyPath, Err: err, Kind: SshErrorKey}
+	}
 	signer, err := ssh.ParsePrivateKey(key)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		// Machines connected to in parallel, and jump hosts, commonly share a key. Prompt once per key and one machine at a time.
+		sshDecryptedLock.Lock()
+		defer sshDecryptedLock.Unlock()
+		if decrypted, ok := sshDecrypted[keyPath]; ok {
+			return ssh.PublicKeys(decrypted), func() {}, nil
+		}
+		passphrase, errPassphrase := sshPassphrase(keyPath)
+		if errPassphrase != nil {
+			return nil, nil, errPassphrase
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, passphrase)
+		if err == nil {
+			sshDecrypted[keyPath] = signer
+		}
+	}
+	if err != nil {
+		return nil, nil, ErrorSsh{Address: keyPath, Err: err, Kind: SshErrorKey}
+	}
+	return ssh.PublicKeys(signer), func() {}, nil
+}
+
+func sshPassphrase(keyPath string) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(SshPassphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("🚫 Private key '%s' is encrypted. Set %s to provide the passphrase non-interactively", keyPath, SshPassphraseEnv)
+	}
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", keyPath)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
-		log.Fatalf("unable to parse private key: %v", err)
+		return nil, fmt.Errorf("unable to read passphrase: %v", err)
 	}
+	return passphrase, nil
+}
+
+// SshConnectOptions controls how connections to machines are established.
+type SshConnectOptions struct {
+	// Backoff is the delay before the first retry. It doubles after each attempt.
+	Backoff time.Duration
+	// CommandTimeout limits each remote command. Zero disables it.
+	CommandTimeout time.Duration
+	// Keepalive is the interval between keepalive requests. The connection is closed after three go unanswered. Zero disables them.
+	Keepalive time.Duration
+	// Retries is the number of additional attempts made when a machine times out or is unreachable, and when read-only commands fail because the connection dropped.
+	Retries int
+	// Timeout limits dialing and the SSH handshake of each attempt. Zero disables it.
+	Timeout time.Duration
+}
+
+// SshConnectDefaults applies to every connection. Programs embedding rove may change it before connecting.
+var SshConnectDefaults = SshConnectOptions{
+	Backoff:   time.Second,
+	Keepalive: 30 * time.Second,
+	Retries:   2,
+	Timeout:   15 * time.Second,
+}
+
+// sshOptions applies the timeouts configured on a machine over SshConnectDefaults.
+func sshOptions(machine *Machine) SshConnectOptions {
+	options := SshConnectDefaults
+	if machine.CommandTimeout > 0 {
+		options.CommandTimeout = time.Duration(machine.CommandTimeout) * time.Second
+	}
+	if machine.ConnectTimeout > 0 {
+		options.Timeout = time.Duration(machine.ConnectTimeout) * time.Second
+	}
+	if machine.Keepalive > 0 {
+		options.Keepalive = time.Duration(machine.Keepalive) * time.Second
+	}
+	return options
+}
+
+// sshDial connects directly, or through the jump client when one is given, retrying transient failures.
+func sshDial(ctx context.Context, jump *ssh.Client, address string, config *ssh.ClientConfig, options SshConnectOptions) (*ssh.Client, error) {
+	backoff := options.Backoff
+	for attempt := 0; ; attempt++ {
+		client, err := sshDialOnce(ctx, jump, address, config, options.Timeout)
+		var errSsh ErrorSsh
+		if err == nil || attempt >= options.Retries || !errors.As(err, &errSsh) || !errSsh.Retryable() {
+			return client, err
+		}
+		fmt.Fprintf(os.Stderr, "Connection to '%s' failed, retrying in %s: %v\n", address, backoff, errSsh.Err)
+		if err := sshSleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// sshSleep waits before a retry, returning early with the context's error when it is canceled.
+func sshSleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func sshDialOnce(ctx context.Context, jump *ssh.Client, address string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var conn net.Conn
+	var err error
+	if jump == nil {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = jump.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, sshDialError(address, err, nil, false)
+	}
+
+	// Channels through jump hosts do not support deadlines, so the handshake is bounded by closing the connection instead.
+	var timedOut atomic.Bool
+	stop := context.AfterFunc(ctx, func() {
+		timedOut.Store(true)
+		conn.Close()
+	})
+	defer stop()
+	var hostKeyErr error
+	handshakeConfig := *config
+	handshakeConfig.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		hostKeyErr = config.HostKeyCallback(hostname, remote, key)
+		return hostKeyErr
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, &handshakeConfig)
+	if err != nil {
+		conn.Close()
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, ctx.Err()
+		}
+		return nil, sshDialError(address, err, hostKeyErr, timedOut.Load())
+	}
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+func sshDialError(address string, err error, hostKeyErr error, timedOut bool) error {
+	var netErr net.Error
+	switch {
+	case hostKeyErr != nil:
+		return ErrorSsh{Address: address, Err: hostKeyErr, Kind: SshErrorHostKey}
+	case timedOut, errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorSsh{Address: address, Err: err, Kind: SshErrorTimeout}
+	// The ssh package does not export a type for client authentication failures.
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return ErrorSsh{Address: address, Err: err, Kind: SshErrorAuth}
+	}
+	return ErrorSsh{Address: address, Err: err, Kind: SshErrorUnreachable}
+}
+
+// sshKeepalive sends keepalive requests on an interval and closes the client after three go unanswered, so that commands on dead links fail instead of hanging. The returned function stops it.
+func sshKeepalive(client *ssh.Client, interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		missed := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			reply := make(chan error, 1)
+			go func() {
+				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+				reply <- err
+			}()
+			select {
+			case <-done:
+				return
+			case err := <-reply:
+				if err != nil {
+					client.Close()
+					return
+				}
+				missed = 0
+			case <-time.After(interval):
+				if missed++; missed >= 3 {
+					client.Close()
+					return
+				}
+			}
+		}
+	}()
+	return sync.OnceFunc(func() { close(done) })
+}
+
+func SshConnect(address string, user string, auth ssh.AuthMethod, hostKeyCallback ssh.HostKeyCallback, jump *ssh.Client, callback func(conn SshRunner, stdin io.Reader) error) error {
+	return sshConnect(context.Background(), address, user, auth, hostKeyCallback, jump, SshConnectDefaults, callback)
+}
+
+// sshConnect passes the callback a connection whose Run method is canceled along with ctx.
+func sshConnect(ctx context.Context, address string, user string, auth ssh.AuthMethod, hostKeyCallback ssh.HostKeyCallback, jump *ssh.Client, options SshConnectOptions, callback func(conn SshRunner, stdin io.Reader) error) error {
 	config := &ssh.ClientConfig{
 		User: user,
 		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
+			auth,
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 	}
-	client, err := ssh.Dial("tcp", address, config)
+	dial := func(ctx context.Context) (*ssh.Client, error) {
+		return sshDial(ctx, jump, address, config, options)
+	}
+	client, err := dial(ctx)
 	if err != nil {
-		log.Fatalf("Failed to dial: %v", err)
+		return err
+	}
+	conn := &SshConnection{
+		Client:        client,
+		Options:       options,
+		ctx:           ctx,
+		dial:          dial,
+		stopKeepalive: sshKeepalive(client, options.Keepalive),
 	}
-	defer client.Close()
-	return callback(&SshConnection{Client: client}, os.Stdin)
+	defer conn.Close()
+	return callback(conn, os.Stdin)
 }
 
 func SshMachine(machine *Machine, callback func(conn SshRunner, stdin io.Reader) error) error {
-	key, err := os.ReadFile(machine.KeyPath)
+	return SshMachineContext(context.Background(), machine, callback)
+}
+
+func SshMachineContext(ctx context.Context, machine *Machine, callback func(conn SshRunner, stdin io.Reader) error) error {
+	return sshMachineWithHostKey(ctx, machine, hostKeyVerify(machine), callback)
+}
+
+func sshMachineWithHostKey(ctx context.Context, machine *Machine, hostKeyCallback ssh.HostKeyCallback, callback func(conn SshRunner, stdin io.Reader) error) error {
+	machine, err := sshConfigResolve(machine)
 	if err != nil {
-		return fmt.Errorf("unable to read private key file: %v", err)
+		return err
 	}
-	return SshConnect(fmt.Sprintf("%s:%d", machine.Address, machine.Port), machine.User, key, callback)
+	auth, closeAuth, err := sshAuth(machine.KeyPath)
+	if err != nil {
+		return err
+	}
+	defer closeAuth()
+	jump, closeJump, err := sshJump(ctx, machine.ProxyJump, machine.User, auth, 0)
+	if err != nil {
+		return err
+	}
+	defer closeJump()
+	return sshConnect(ctx, fmt.Sprintf("%s:%d", machine.Address, machine.Port), machine.User, auth, hostKeyCallback, jump, sshOptions(machine), callback)
 }
 
 func SshMachineByName(local bool, name string, callback func(conn SshRunner, stdin io.Reader) error) error {
+	return SshMachineByNameContext(context.Background(), local, name, callback)
+}
+
+// SshMachineByNameContext connects to the named machine, or the default machine when name is empty. Commands run through the connection are canceled along with ctx.
+func SshMachineByNameContext(ctx context.Context, local bool, name string, callback func(conn SshRunner, stdin io.Reader) error) error {
 	if local {
-		return callback(&LocalRunner{}, os.Stdin)
+		return callback(&LocalRunner{ctx: ctx}, os.Stdin)
+	}
+	machine, err := machineByName(name)
+	if err != nil {
+		return err
+	}
+	return SshMachineContext(ctx, machine, callback)
+}
+
+// machineByName returns the named machine, or the default machine when name is empty. The machine described by ROVE_MACHINE_* environment variables takes precedence over the default, and is never stored.
+func machineByName(name string) (*Machine, error) {
+	// Environment variables are only validated when they select the machine, so that a stray ROVE_MACHINE_ADDRESS does not break --machine.
+	if envName := machineEnvName(); envName != "" && (name == "" || name == envName) {
+		return machineEnv()
 	}
 	name = cmp.Or(name, GetPreference(DefaultMachine))
+	if name == "" && databasePath == "" {
+		return nil, fmt.Errorf("🚫 No config file found. Rove looks for .rove in this directory and its parents, then %s. Run `rove machine add` to configure a machine, or pass --config or set ROVE_CONFIG", configUserPath())
+	}
 	if name == "" {
-		return errors.New("🚫 No machine specified. Either run `rove machine use [NAME]` to set the default, or use the `--machine [NAME]` flag on individual commands")
-	}
-	return trance.Query[Machine]().
-		Filter("name", "=", name).
-		First().
-		OnError(func(err error) error {
-			if err != nil && errors.Is(err, trance.ErrorNotFound{}) {
-				return fmt.Errorf("🚫 No machine with name '%s' configured", name)
-			}
-			return err
-		}).
-		Then(func(machine *Machine) error {
-			return SshMachine(machine, callback)
-		}).
-		Error
+		return nil, errors.New("🚫 No machine specified. Either run `rove machine use [NAME]` to set the default, or use the `--machine [NAME]` flag on individual commands")
+	}
+	machine, err := trance.Query[Machine]().Filter("name", "=", name).CollectFirst()
+	if err != nil {
+		if errors.Is(err, trance.ErrorNotFound{}) {
+			return nil, fmt.Errorf("🚫 No machine with name '%s' configured", name)
+		}
+		return nil, err
+	}
+	return machine, nil
 }
 
 type SshConnection struct {
-	Client *ssh.Client
-	Err    error
+	Client  *ssh.Client
+	Err     error
+	Options SshConnectOptions
+
+	ctx           context.Context
+	dial          func(context.Context) (*ssh.Client, error)
+	stderr        io.Writer
+	stopKeepalive func()
+}
+
+// sshReadOnlyCommand matches commands that are safe to run again after the connection drops.
+var sshReadOnlyCommand = regexp.MustCompile(`^docker (info|inspect|version|(config|network|node|secret|service|volume) (inspect|ls|ps))( |$)`)
+
+func (conn *SshConnection) Close() error {
+	if conn.stopKeepalive != nil {
+		conn.stopKeepalive()
+	}
+	return conn.Client.Close()
 }
 
 func (conn *SshConnection) Error() error {
@@ -120,41 +442,157 @@ func (conn *SshConnection) OnError(callback func(error) error) SshRunner {
 	return conn
 }
 
+func (conn *SshConnection) Stderr() io.Writer {
+	if conn.stderr != nil {
+		return conn.stderr
+	}
+	return os.Stderr
+}
+
+func (conn *SshConnection) Stdout() io.Writer {
+	return os.Stdout
+}
+
+func (conn *SshConnection) setStderr(stderr io.Writer) {
+	conn.stderr = stderr
+}
+
 func (conn *SshConnection) Run(command string, callback func(string) error) SshRunner {
+	return conn.RunContext(runnerContext(conn.ctx), command, callback)
+}
+
+// RunContext runs the command, and on cancellation signals the remote process and closes its session.
+func (conn *SshConnection) RunContext(ctx context.Context, command string, callback func(string) error) SshRunner {
 	if conn.Err != nil {
 		return conn
 	}
+	stdout, err := conn.run(ctx, command)
+	backoff := conn.Options.Backoff
+	for attempt := 0; err != nil && attempt < conn.Options.Retries && conn.dial != nil && sshReadOnlyCommand.MatchString(command) && sshTransient(err); attempt++ {
+		fmt.Fprintf(conn.Stderr(), "Command '%s' failed, reconnecting in %s: %v\n", command, backoff, err)
+		if err = sshSleep(ctx, backoff); err != nil {
+			break
+		}
+		backoff *= 2
+		if err = conn.reconnect(ctx); err == nil {
+			stdout, err = conn.run(ctx, command)
+		}
+	}
+	if err != nil {
+		conn.Err = err
+		return conn
+	}
+	conn.Err = callback(stdout)
+	return conn
+}
+
+func (conn *SshConnection) reconnect(ctx context.Context) error {
+	conn.Close()
+	client, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	conn.Client = client
+	conn.stopKeepalive = sshKeepalive(client, conn.Options.Keepalive)
+	return nil
+}
+
+func (conn *SshConnection) run(ctx context.Context, command string) (string, error) {
+	var bufferStderr bytes.Buffer
 	var bufferStdout bytes.Buffer
 	session, err := conn.Client.NewSession()
 	if err != nil {
-		conn.Err = fmt.Errorf("failed to create session for command '%s': %v", command, err)
-		return conn
+		return "", fmt.Errorf("failed to create session for command '%s': %w", command, err)
 	}
 	defer session.Close()
-	session.Stderr = os.Stderr
+	session.Stderr = &bufferStderr
 	session.Stdout = &bufferStdout
+	ctx, release := conn.commandContext(ctx, session)
+	defer release()
 	if err := session.Run(command); err != nil {
-		conn.Err = fmt.Errorf("failed to run command '%s': %v", command, err)
-		return conn
+		return "", commandError(ctx, command, err, bufferStderr.String())
+	}
+	conn.Stderr().Write(bufferStderr.Bytes())
+	return bufferStdout.String(), nil
+}
+
+// commandContext applies the command timeout to ctx, and signals and closes the session once ctx ends. The returned function releases both.
+func (conn *SshConnection) commandContext(ctx context.Context, session *ssh.Session) (context.Context, func()) {
+	cancel := context.CancelFunc(func() {})
+	if timeout := conn.Options.CommandTimeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// commandError attributes failures to cancellation when ctx has ended, and otherwise to the command along with its captured stderr.
+func commandError(ctx context.Context, command string, err error, stderr string) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return fmt.Errorf("command '%s' canceled: %w", command, ctx.Err())
+	case context.DeadlineExceeded:
+		return fmt.Errorf("command '%s' timed out: %w", command, ctx.Err())
+	}
+	exitCode := -1
+	var exitErrLocal *exec.ExitError
+	var exitErrSsh *ssh.ExitError
+	if errors.As(err, &exitErrSsh) {
+		exitCode = exitErrSsh.ExitStatus()
+	} else if errors.As(err, &exitErrLocal) {
+		exitCode = exitErrLocal.ExitCode()
+	}
+	return CommandError{
+		Command:  command,
+		Err:      err,
+		ExitCode: exitCode,
+		Stderr:   stderr,
 	}
-	conn.Err = callback(bufferStdout.String())
-	return conn
+}
+
+// sshTransient reports whether a command failed because of the connection rather than by exiting with an error status or being canceled.
+func sshTransient(err error) bool {
+	var exitErr *ssh.ExitError
+	return !errors.As(err, &exitErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// runnerContext falls back to the background context for runners that were not created with one.
+func runnerContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
 }
 
 func confirmDeployment(force bool, stdin io.Reader) error {
+	return confirmDeploymentTo(os.Stdout, force, stdin)
+}
+
+// confirmDeploymentTo writes prompts to out, so that commands with JSON output may keep STDOUT parsable.
+func confirmDeploymentTo(out io.Writer, force bool, stdin io.Reader) error {
+	return confirmPrompt(out, force, stdin, "Do you want Rove to run this deployment?", "🚫 Deployment canceled because response did not match 'yes'")
+}
+
+func confirmPrompt(out io.Writer, force bool, stdin io.Reader, question string, denied string) error {
 	if force {
-		fmt.Println("\nConfirmations skipped.")
+		fmt.Fprintln(out, "\nConfirmations skipped.")
 	} else {
-		fmt.Println("\nDo you want Rove to run this deployment?")
-		fmt.Println("  Type 'yes' to approve, or anything else to deny.")
-		fmt.Print("  Enter a value: ")
+		fmt.Fprintln(out, "\n"+question)
+		fmt.Fprintln(out, "  Type 'yes' to approve, or anything else to deny.")
+		fmt.Fprint(out, "  Enter a value: ")
 		line, err := bufio.NewReader(stdin).ReadString('\n')
 		if err != nil {
-			fmt.Println("🚫 Could not read from STDIN")
+			fmt.Fprintln(out, "🚫 Could not read from STDIN")
 			return err
 		}
 		if strings.ToLower(strings.TrimSpace(line)) != "yes" {
-			return errors.New("🚫 Deployment canceled because response did not match 'yes'")
+			return errors.New(denied)
 		}
 	}
 	return nil
@@ -164,12 +602,21 @@ type SshRunner interface {
 	Error() error
 	OnError(func(error) error) SshRunner
 	Run(string, func(string) error) SshRunner
+	RunContext(context.Context, string, func(string) error) SshRunner
+	Stream(string, StreamHandler) SshRunner
+	StreamContext(context.Context, string, StreamHandler) SshRunner
+	// Stderr is where commands print progress and the stderr of remote commands. Like Stdout, it is buffered per machine when running on several machines at once.
+	Stderr() io.Writer
+	// Stdout is where commands print their output, which differs from os.Stdout when running on several machines at once.
+	Stdout() io.Writer
 }
 
 type SshConnectionMock struct {
 	CommandsRun []string
 	Err         error
-	Result      string
+	// Errors fails the commands it contains with the given error.
+	Errors map[string]error
+	Result string
 }
 
 func (conn *SshConnectionMock) Error() error {
@@ -183,11 +630,31 @@ func (conn *SshConnectionMock) OnError(callback func(error) error) SshRunner {
 	return conn
 }
 
+func (conn *SshConnectionMock) Stderr() io.Writer {
+	return os.Stderr
+}
+
+func (conn *SshConnectionMock) Stdout() io.Writer {
+	return os.Stdout
+}
+
 func (conn *SshConnectionMock) Run(command string, callback func(string) error) SshRunner {
+	return conn.RunContext(context.Background(), command, callback)
+}
+
+func (conn *SshConnectionMock) RunContext(ctx context.Context, command string, callback func(string) error) SshRunner {
 	if conn.Err != nil {
 		return conn
 	}
+	if err := ctx.Err(); err != nil {
+		conn.Err = fmt.Errorf("command '%s' canceled: %w", command, err)
+		return conn
+	}
 	conn.CommandsRun = append(conn.CommandsRun, command)
+	if err, ok := conn.Errors[command]; ok {
+		conn.Err = err
+		return conn
+	}
 	conn.Err = callback(conn.Result)
 	return conn
 }
@@ -0,0 +1,95 @@
+package rove
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kevinburke/ssh_config"
+)
+
+func sshConfigLoad() (*ssh_config.Config, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(home, ".ssh", "config"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &ssh_config.Config{}, nil
+		}
+		return nil, fmt.Errorf("unable to read SSH config: %v", err)
+	}
+	defer file.Close()
+	config, err := ssh_config.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SSH config: %v", err)
+	}
+	return config, nil
+}
+
+// sshConfigResolve overlays HostName, User, Port, IdentityFile and ProxyJump from ~/.ssh/config onto machines that reference an SSH config alias. Stored values are used as fallbacks.
+func sshConfigResolve(machine *Machine) (*Machine, error) {
+	if machine.SshConfigHost == "" {
+		return machine, nil
+	}
+	config, err := sshConfigLoad()
+	if err != nil {
+		return nil, err
+	}
+	return sshConfigApply(config, machine)
+}
+
+func sshConfigApply(config *ssh_config.Config, machine *Machine) (*Machine, error) {
+	alias := machine.SshConfigHost
+	values := make(map[string]string)
+	for _, key := range []string{"HostName", "IdentityFile", "Port", "ProxyJump", "User"} {
+		value, err := config.Get(alias, key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s' for host '%s' from SSH config: %v", key, alias, err)
+		}
+		values[key] = value
+	}
+
+	resolved := *machine
+	resolved.Address = cmp.Or(values["HostName"], machine.Address, alias)
+	resolved.KeyPath = cmp.Or(sshConfigExpandHome(values["IdentityFile"]), machine.KeyPath)
+	resolved.ProxyJump = cmp.Or(values["ProxyJump"], machine.ProxyJump)
+	if values["ProxyJump"] == "none" {
+		// Like OpenSSH, "ProxyJump none" disables a jump host that would otherwise apply.
+		resolved.ProxyJump = ""
+	}
+	resolved.User = cmp.Or(values["User"], machine.User)
+	if values["Port"] != "" {
+		port, err := strconv.ParseInt(values["Port"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port '%s' for host '%s' in SSH config", values["Port"], alias)
+		}
+		resolved.Port = port
+	}
+	if resolved.Port == 0 {
+		resolved.Port = 22
+	}
+	if resolved.User == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("no user configured for host '%s': %v", alias, err)
+		}
+		resolved.User = current.Username
+	}
+	return &resolved, nil
+}
+
+func sshConfigExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
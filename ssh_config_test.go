@@ -0,0 +1,88 @@
+package rove
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kevinburke/ssh_config"
+)
+
+func TestSshConfigApply(t *testing.T) {
+	config, err := ssh_config.DecodeBytes([]byte(`Host web
+  HostName 203.0.113.10
+  User deploy
+  Port 2222
+  IdentityFile ~/.ssh/web
+  ProxyJump bastion
+
+Host bare
+  User admin
+
+Host broken
+  Port ssh
+
+Host direct
+  HostName 203.0.113.20
+  ProxyJump none
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	machine, err := sshConfigApply(config, &Machine{
+		Address:       "198.51.100.1",
+		KeyPath:       "/tmp/key",
+		Port:          22,
+		SshConfigHost: "web",
+		User:          "root",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Machine{
+		Address:       "203.0.113.10",
+		KeyPath:       filepath.Join(home, ".ssh", "web"),
+		Port:          2222,
+		ProxyJump:     "bastion",
+		SshConfigHost: "web",
+		User:          "deploy",
+	}
+	if *machine != expected {
+		t.Errorf("'%#v' did not match expected '%#v'.", *machine, expected)
+	}
+
+	machine, err = sshConfigApply(config, &Machine{
+		KeyPath:       "/tmp/key",
+		SshConfigHost: "bare",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = Machine{
+		Address:       "bare",
+		KeyPath:       "/tmp/key",
+		Port:          22,
+		SshConfigHost: "bare",
+		User:          "admin",
+	}
+	if *machine != expected {
+		t.Errorf("'%#v' did not match expected '%#v'.", *machine, expected)
+	}
+
+	machine, err = sshConfigApply(config, &Machine{ProxyJump: "bastion", SshConfigHost: "direct", User: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if machine.ProxyJump != "" {
+		t.Errorf("expected 'ProxyJump none' to disable stored jump host, got '%s'.", machine.ProxyJump)
+	}
+
+	if _, err := sshConfigApply(config, &Machine{SshConfigHost: "broken", User: "root"}); err == nil {
+		t.Error("expected error for invalid port.")
+	}
+}
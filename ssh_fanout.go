@@ -0,0 +1,204 @@
+package rove
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// sshFanOutParallel limits how many machines are connected to at once.
+const sshFanOutParallel = 8
+
+type SshMachinesJson struct {
+	Machines []SshMachineResultJson `json:"machines"`
+}
+
+type SshMachineResultJson struct {
+	Error  *ErrorJson `json:"error,omitempty"`
+	Name   string     `json:"name"`
+	Output any        `json:"output,omitempty"`
+}
+
+// SshMachinesContext runs callback on every machine in group, or on every configured machine when all is set. Machines run in parallel, and each one's output is buffered and printed after it finishes, followed by a summary. Without a group or all, it behaves like SshMachineByNameContext.
+func SshMachinesContext(ctx context.Context, local bool, name string, group string, all bool, jsonOutput bool, callback func(conn SshRunner, stdin io.Reader) error) error {
+	if group == "" && !all {
+		return SshMachineByNameContext(ctx, local, name, callback)
+	}
+	if local || name != "" {
+		return errors.New("🚫 The `--local` and `--machine` flags cannot be combined with `--group` or `--all-machines`")
+	}
+	if group != "" && all {
+		return errors.New("🚫 The `--group` and `--all-machines` flags cannot be combined")
+	}
+	machines, err := machinesSelect(group)
+	if err != nil {
+		return err
+	}
+	if len(machines) == 0 {
+		return errors.New("🚫 No machines configured")
+	}
+	return sshFanOut(ctx, machines, jsonOutput, SshMachineContext, callback)
+}
+
+func sshFanOut(ctx context.Context, machines []*Machine, jsonOutput bool, connect func(context.Context, *Machine, func(SshRunner, io.Reader) error) error, callback func(conn SshRunner, stdin io.Reader) error) error {
+	outputs := make([]bytes.Buffer, len(machines))
+	stderrs := make([]bytes.Buffer, len(machines))
+	errs := make([]error, len(machines))
+	limit := make(chan struct{}, sshFanOutParallel)
+	var wg sync.WaitGroup
+	for i, machine := range machines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			errs[i] = connect(ctx, machine, func(conn SshRunner, _ io.Reader) error {
+				// Remote stderr is buffered too, so that progress from parallel machines does not interleave.
+				if redirect, ok := conn.(sshStderrRedirect); ok {
+					redirect.setStderr(&stderrs[i])
+				}
+				return callback(&sshOutput{conn: conn, errOut: &stderrs[i], out: &outputs[i]}, sshFanOutStdin{})
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i, machine := range machines {
+		if out := stderrs[i].String(); out != "" {
+			fmt.Fprintf(os.Stderr, "==> %s\n%s\n", machine.Name, strings.TrimSuffix(out, "\n"))
+		}
+	}
+
+	failed := 0
+	pending := 0
+	for _, err := range errs {
+		var errExit ErrorExit
+		if errors.As(err, &errExit) && errExit.Code == ExitChangesPending && errExit.Err == nil {
+			pending++
+		} else if err != nil {
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		output := SshMachinesJson{
+			Machines: make([]SshMachineResultJson, 0, len(machines)),
+		}
+		for i, machine := range machines {
+			result := SshMachineResultJson{
+				Name: machine.Name,
+			}
+			if raw := bytes.TrimSpace(outputs[i].Bytes()); json.Valid(raw) {
+				result.Output = json.RawMessage(raw)
+			} else if len(raw) > 0 {
+				result.Output = string(raw)
+			}
+			var errExit ErrorExit
+			if errs[i] != nil && !(errors.As(errs[i], &errExit) && errExit.Err == nil) {
+				errJson := errorJson(errs[i])
+				result.Error = &errJson
+			}
+			output.Machines = append(output.Machines, result)
+		}
+		out, err := json.MarshalIndent(output, "", "    ")
+		if err != nil {
+			fmt.Println("🚫 Could not format JSON:\n", output)
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		for i, machine := range machines {
+			fmt.Printf("==> %s\n", machine.Name)
+			if out := outputs[i].String(); out != "" {
+				fmt.Println(strings.TrimSuffix(out, "\n"))
+			}
+			if errs[i] != nil && !errors.As(errs[i], &ErrorExit{}) {
+				fmt.Println(errs[i])
+			}
+			fmt.Println()
+		}
+		fmt.Println("Summary:")
+		for i, machine := range machines {
+			var errExit ErrorExit
+			switch {
+			case errs[i] == nil:
+				fmt.Printf(" ✅ %s\n", machine.Name)
+			case errors.As(errs[i], &errExit) && errExit.Code == ExitChangesPending && errExit.Err == nil:
+				fmt.Printf(" ~ %s: changes pending\n", machine.Name)
+			default:
+				fmt.Printf(" 🚫 %s: %s\n", machine.Name, strings.SplitN(errs[i].Error(), "\n", 2)[0])
+			}
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return ErrorExit{Code: 1, Err: fmt.Errorf("🚫 Failed on %d of %d machines", failed, len(machines))}
+	}
+	if pending > 0 {
+		return ErrorExit{Code: ExitChangesPending}
+	}
+	return nil
+}
+
+// sshFanOutStdin refuses confirmation prompts, which cannot be answered for several machines at once.
+type sshFanOutStdin struct{}
+
+func (sshFanOutStdin) Read([]byte) (int, error) {
+	return 0, errors.New("🚫 Confirmations are not supported when targeting multiple machines. Use --force")
+}
+
+// sshStderrRedirect is implemented by runners that can write the stderr of remote commands somewhere other than os.Stderr.
+type sshStderrRedirect interface {
+	setStderr(io.Writer)
+}
+
+// sshOutput sends a runner's output to out instead of os.Stdout, and its progress to errOut instead of os.Stderr.
+type sshOutput struct {
+	conn   SshRunner
+	errOut io.Writer
+	out    io.Writer
+}
+
+func (conn *sshOutput) Error() error {
+	return conn.conn.Error()
+}
+
+func (conn *sshOutput) OnError(callback func(error) error) SshRunner {
+	conn.conn.OnError(callback)
+	return conn
+}
+
+func (conn *sshOutput) Run(command string, callback func(string) error) SshRunner {
+	conn.conn.Run(command, callback)
+	return conn
+}
+
+func (conn *sshOutput) RunContext(ctx context.Context, command string, callback func(string) error) SshRunner {
+	conn.conn.RunContext(ctx, command, callback)
+	return conn
+}
+
+func (conn *sshOutput) Stderr() io.Writer {
+	return conn.errOut
+}
+
+func (conn *sshOutput) Stdout() io.Writer {
+	return conn.out
+}
+
+func (conn *sshOutput) Stream(command string, handler StreamHandler) SshRunner {
+	conn.conn.Stream(command, handler)
+	return conn
+}
+
+func (conn *sshOutput) StreamContext(ctx context.Context, command string, handler StreamHandler) SshRunner {
+	conn.conn.StreamContext(ctx, command, handler)
+	return conn
+}
@@ -0,0 +1,139 @@
+package rove
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestSshFanOut(t *testing.T) {
+	machines := []*Machine{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	connect := func(ctx context.Context, machine *Machine, callback func(SshRunner, io.Reader) error) error {
+		if machine.Name == "c" {
+			return errors.New("unreachable")
+		}
+		return callback(&SshConnectionMock{Result: machine.Name}, nil)
+	}
+	callback := func(conn SshRunner, stdin io.Reader) error {
+		return conn.Run("docker info", func(res string) error {
+			_, err := conn.Stdout().Write([]byte(`{"name":"` + res + `"}`))
+			return err
+		}).Error()
+	}
+
+	var err error
+	capture(t).Run(func() error {
+		err = sshFanOut(context.Background(), machines, false, connect, callback)
+		return nil
+	}).ExpectStdout(`==> a
+{"name":"a"}
+
+==> b
+{"name":"b"}
+
+==> c
+unreachable
+
+Summary:
+ ✅ a
+ ✅ b
+ 🚫 c: unreachable
+
+`)
+	var errExit ErrorExit
+	if !errors.As(err, &errExit) || errExit.Code != 1 {
+		t.Errorf("expected exit status 1, got %v", err)
+	}
+
+	capture(t).Run(func() error {
+		err = sshFanOut(context.Background(), machines[:2], true, connect, callback)
+		return nil
+	}).ExpectStdout(`{
+    "machines": [
+        {
+            "name": "a",
+            "output": {
+                "name": "a"
+            }
+        },
+        {
+            "name": "b",
+            "output": {
+                "name": "b"
+            }
+        }
+    ]
+}
+`)
+	if err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+
+	capture(t).Run(func() error {
+		err = sshFanOut(context.Background(), machines[:1], false, connect, func(conn SshRunner, stdin io.Reader) error {
+			return confirmDeploymentTo(conn.Stdout(), false, stdin)
+		})
+		return nil
+	})
+	if err == nil {
+		t.Error("expected confirmation prompt to fail when targeting multiple machines.")
+	}
+
+	capture(t).Run(func() error {
+		err = sshFanOut(context.Background(), machines[:1], false, connect, func(conn SshRunner, stdin io.Reader) error {
+			return ErrorExit{Code: ExitChangesPending}
+		})
+		return nil
+	})
+	if !errors.As(err, &errExit) || errExit.Code != ExitChangesPending {
+		t.Errorf("expected exit status %d, got %v", ExitChangesPending, err)
+	}
+}
+
+func TestSshFanOutStderr(t *testing.T) {
+	machines := []*Machine{{Name: "a"}, {Name: "b"}}
+	connect := func(ctx context.Context, machine *Machine, callback func(SshRunner, io.Reader) error) error {
+		return callback(&LocalRunner{}, nil)
+	}
+	callback := func(conn SshRunner, stdin io.Reader) error {
+		fmt.Fprintln(conn.Stderr(), "step 1")
+		fmt.Fprintln(conn.Stderr(), "step 2")
+		return nil
+	}
+
+	savedStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	capture(t).Run(func() error {
+		return sshFanOut(context.Background(), machines, true, connect, callback)
+	})
+	os.Stderr = savedStderr
+	w.Close()
+	stderr, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "==> a\nstep 1\nstep 2\n==> b\nstep 1\nstep 2\n"
+	if string(stderr) != expected {
+		t.Errorf("'%s' STDERR was not grouped by machine. Expected '%s'.", stderr, expected)
+	}
+}
+
+func TestSshMachinesContextFlags(t *testing.T) {
+	noop := func(conn SshRunner, stdin io.Reader) error { return nil }
+	if err := SshMachinesContext(context.Background(), true, "", "prod", false, false, noop); err == nil {
+		t.Error("expected --local with --group to fail.")
+	}
+	if err := SshMachinesContext(context.Background(), false, "a", "", true, false, noop); err == nil {
+		t.Error("expected --machine with --all-machines to fail.")
+	}
+	if err := SshMachinesContext(context.Background(), false, "", "prod", true, false, noop); err == nil {
+		t.Error("expected --group with --all-machines to fail.")
+	}
+}
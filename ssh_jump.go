@@ -0,0 +1,174 @@
+package rove
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/evantbyrne/trance"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const sshJumpMaxDepth = 8
+
+// parseProxyJump splits an ad-hoc jump host in [user@]host[:port] form, falling back to defaultUser and port 22.
+func parseProxyJump(jump string, defaultUser string) (string, string, error) {
+	user := defaultUser
+	address := jump
+	if at := strings.LastIndex(jump, "@"); at != -1 {
+		user = jump[:at]
+		address = jump[at+1:]
+	}
+	if address == "" || user == "" {
+		return "", "", fmt.Errorf("🚫 Invalid proxy jump '%s'. Use the name of a configured machine or user@host:port", jump)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "22")
+	}
+	return user, address, nil
+}
+
+// sshJumpSplit splits a comma-separated ProxyJump chain into the hops before the last one and the last hop, which is dialed through them.
+func sshJumpSplit(jump string) (string, string) {
+	if i := strings.LastIndex(jump, ","); i != -1 {
+		return strings.TrimSpace(jump[:i]), strings.TrimSpace(jump[i+1:])
+	}
+	return "", strings.TrimSpace(jump)
+}
+
+// sshJumpRename replaces every hop of a comma-separated jump chain that names the machine from with to.
+func sshJumpRename(jump string, from string, to string) string {
+	if jump == "" {
+		return jump
+	}
+	hops := strings.Split(jump, ",")
+	for i, hop := range hops {
+		if strings.TrimSpace(hop) == from {
+			hops[i] = to
+		}
+	}
+	return strings.Join(hops, ",")
+}
+
+// sshJump connects to a configured machine, or to an ad-hoc host or SSH config alias that must be listed in ~/.ssh/known_hosts. Comma-separated chains are connected in order, and "none" disables the jump. The returned function closes every connection in the chain.
+func sshJump(ctx context.Context, jump string, user string, auth ssh.AuthMethod, depth int) (*ssh.Client, func(), error) {
+	chain, jump := sshJumpSplit(jump)
+	if jump == "" || (jump == "none" && chain == "") {
+		return nil, func() {}, nil
+	}
+	if depth >= sshJumpMaxDepth {
+		return nil, nil, fmt.Errorf("🚫 Proxy jump chain exceeds %d hosts. Check machines for circular proxy jumps", sshJumpMaxDepth)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	alias := false
+	machine, err := trance.Query[Machine]().Filter("name", "=", jump).CollectFirst()
+	if err == nil {
+		hostKeyCallback = hostKeyVerify(machine)
+	} else if errors.Is(err, trance.ErrorNotFound{}) {
+		machine, err = sshJumpAlias(jump, user)
+		if err == nil && machine != nil {
+			alias = true
+			hostKeyCallback, err = sshKnownHosts(jump)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if machine != nil {
+		if machine, err = sshConfigResolve(machine); err != nil {
+			return nil, nil, err
+		}
+		// SSH config aliases without an IdentityFile reuse the target's credentials, like ad-hoc jump hosts.
+		jumpAuth, closeAuth := auth, func() {}
+		if !alias || machine.KeyPath != "" {
+			if jumpAuth, closeAuth, err = sshAuth(machine.KeyPath); err != nil {
+				return nil, nil, err
+			}
+		}
+		// Earlier hops in a chain take the place of the jump host's own ProxyJump, as they do with OpenSSH.
+		parent, closeParent, err := sshJump(ctx, cmp.Or(chain, machine.ProxyJump), machine.User, jumpAuth, depth+1)
+		if err != nil {
+			closeAuth()
+			return nil, nil, err
+		}
+		client, err := sshDial(ctx, parent, fmt.Sprintf("%s:%d", machine.Address, machine.Port), &ssh.ClientConfig{
+			User:            machine.User,
+			Auth:            []ssh.AuthMethod{jumpAuth},
+			HostKeyCallback: hostKeyCallback,
+		}, sshOptions(machine))
+		if err != nil {
+			closeParent()
+			closeAuth()
+			return nil, nil, fmt.Errorf("unable to connect to jump host '%s': %w", jump, err)
+		}
+		return client, func() {
+			client.Close()
+			closeParent()
+			closeAuth()
+		}, nil
+	}
+
+	jumpUser, address, err := parseProxyJump(jump, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hostKeyCallback, err = sshKnownHosts(jump); err != nil {
+		return nil, nil, err
+	}
+	parent, closeParent, err := sshJump(ctx, chain, user, auth, depth+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := sshDial(ctx, parent, address, &ssh.ClientConfig{
+		User:            jumpUser,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+	}, SshConnectDefaults)
+	if err != nil {
+		closeParent()
+		return nil, nil, fmt.Errorf("unable to connect to jump host '%s': %w", jump, err)
+	}
+	return client, func() {
+		client.Close()
+		closeParent()
+	}, nil
+}
+
+// sshJumpAlias returns an unsaved machine when the jump host is a Host alias with a HostName in ~/.ssh/config, which is how ProxyJump values in that file usually refer to bastions.
+func sshJumpAlias(jump string, user string) (*Machine, error) {
+	if strings.ContainsAny(jump, "@:") {
+		return nil, nil
+	}
+	config, err := sshConfigLoad()
+	if err != nil {
+		return nil, err
+	}
+	hostName, err := config.Get(jump, "HostName")
+	if err != nil || hostName == "" {
+		return nil, err
+	}
+	return &Machine{
+		Name:          jump,
+		SshConfigHost: jump,
+		User:          user,
+	}, nil
+}
+
+func sshKnownHosts(jump string) (ssh.HostKeyCallback, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("🚫 Ad-hoc jump host '%s' must be listed in ~/.ssh/known_hosts: %v", jump, err)
+	}
+	return hostKeyCallback, nil
+}
@@ -0,0 +1,94 @@
+package rove
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	tests := []struct {
+		jump    string
+		user    string
+		address string
+	}{
+		{"bastion.example.com", "deploy", "bastion.example.com:22"},
+		{"admin@bastion.example.com", "admin", "bastion.example.com:22"},
+		{"admin@bastion.example.com:2222", "admin", "bastion.example.com:2222"},
+		{"192.0.2.1:2222", "deploy", "192.0.2.1:2222"},
+		{"admin@[2001:db8::1]:2222", "admin", "[2001:db8::1]:2222"},
+		{"[2001:db8::1]", "deploy", "[2001:db8::1]:22"},
+	}
+	for _, test := range tests {
+		user, address, err := parseProxyJump(test.jump, "deploy")
+		if err != nil {
+			t.Errorf("'%s': %v", test.jump, err)
+			continue
+		}
+		if user != test.user || address != test.address {
+			t.Errorf("'%s' parsed as '%s' '%s', expected '%s' '%s'.", test.jump, user, address, test.user, test.address)
+		}
+	}
+
+	for _, jump := range []string{"admin@", "@bastion.example.com"} {
+		if _, _, err := parseProxyJump(jump, "deploy"); err == nil {
+			t.Errorf("expected '%s' to be invalid.", jump)
+		}
+	}
+}
+
+func TestSshJumpMaxDepth(t *testing.T) {
+	if _, _, err := sshJump(context.Background(), "bastion", "deploy", nil, sshJumpMaxDepth); err == nil {
+		t.Error("expected proxy jump chain exceeding max depth to fail.")
+	}
+}
+
+func TestSshJumpSplit(t *testing.T) {
+	tests := []struct {
+		jump  string
+		chain string
+		hop   string
+	}{
+		{"bastion", "", "bastion"},
+		{"outer,inner", "outer", "inner"},
+		{"admin@outer:2222, middle ,inner", "admin@outer:2222, middle", "inner"},
+	}
+	for _, test := range tests {
+		chain, hop := sshJumpSplit(test.jump)
+		if chain != test.chain || hop != test.hop {
+			t.Errorf("'%s' split as '%s' '%s', expected '%s' '%s'.", test.jump, chain, hop, test.chain, test.hop)
+		}
+	}
+}
+
+func TestSshJumpNone(t *testing.T) {
+	client, closeJump, err := sshJump(context.Background(), "none", "deploy", nil, 0)
+	if err != nil || client != nil {
+		t.Fatalf("expected 'none' to disable the jump host, got '%v' '%v'.", client, err)
+	}
+	closeJump()
+}
+
+func TestSshJumpChain(t *testing.T) {
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	if err := testDatabase(func() error {
+		// The last hop is reached through the earlier ones, so a two-hop chain one level below the limit must recurse past it.
+		_, _, err := sshJump(context.Background(), "admin@outer.example.com,admin@inner.example.com", "deploy", nil, sshJumpMaxDepth-1)
+		if err == nil || !strings.Contains(err.Error(), "Proxy jump chain exceeds") {
+			t.Errorf("expected chained proxy jump to recurse through earlier hops, got %v", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+package rove
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// StreamHandler receives the output of a command line by line while it runs, without trailing newlines. When Stderr is nil, stderr is captured like it is by Run: attached to the CommandError on failure, and otherwise written to the runner's Stderr once the command exits. Stdout lines are discarded when Stdout is nil. Handlers are never called concurrently, and an error from either one stops the command.
+type StreamHandler struct {
+	Stderr func(string) error
+	Stdout func(string) error
+}
+
+const streamMaxLine = 1024 * 1024
+
+// streamLines delivers lines from stdout and stderr to the handler until both reach EOF, and returns stderr that was captured for lack of a handler. The first handler error calls abort, and remaining output is discarded.
+func streamLines(stdout io.Reader, stderr io.Reader, handler StreamHandler, abort func()) (string, error) {
+	var captured strings.Builder
+	if handler.Stderr == nil {
+		handler.Stderr = func(line string) error {
+			captured.WriteString(line + "\n")
+			return nil
+		}
+	}
+	var lock sync.Mutex
+	var handlerErr error
+	var wait sync.WaitGroup
+	scan := func(reader io.Reader, callback func(string) error) {
+		defer wait.Done()
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), streamMaxLine)
+		for scanner.Scan() {
+			lock.Lock()
+			if handlerErr == nil && callback != nil {
+				if err := callback(scanner.Text()); err != nil {
+					handlerErr = err
+					abort()
+				}
+			}
+			lock.Unlock()
+		}
+		// Keep reading after oversized lines so that the command does not block on a full pipe.
+		io.Copy(io.Discard, reader)
+	}
+	wait.Add(2)
+	go scan(stdout, handler.Stdout)
+	go scan(stderr, handler.Stderr)
+	wait.Wait()
+	return captured.String(), handlerErr
+}
+
+func (conn *LocalRunner) Stream(command string, handler StreamHandler) SshRunner {
+	return conn.StreamContext(runnerContext(conn.ctx), command, handler)
+}
+
+func (conn *LocalRunner) StreamContext(ctx context.Context, command string, handler StreamHandler) SshRunner {
+	if conn.Err != nil {
+		return conn
+	}
+	words, err := shellquote.Split(command)
+	if err != nil {
+		conn.Err = fmt.Errorf("failed to shell split command '%s': %v", command, err)
+		return conn
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		conn.Err = fmt.Errorf("failed to run command '%s': %v", command, err)
+		return conn
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		conn.Err = fmt.Errorf("failed to run command '%s': %v", command, err)
+		return conn
+	}
+	if err := cmd.Start(); err != nil {
+		conn.Err = fmt.Errorf("failed to run command '%s': %v", command, err)
+		return conn
+	}
+	captured, handlerErr := streamLines(stdout, stderr, handler, cancel)
+	err = cmd.Wait()
+	switch {
+	case handlerErr != nil:
+		conn.Err = handlerErr
+	case err != nil:
+		conn.Err = commandError(ctx, command, err, captured)
+	default:
+		fmt.Fprint(conn.Stderr(), captured)
+	}
+	return conn
+}
+
+func (conn *SshConnection) Stream(command string, handler StreamHandler) SshRunner {
+	return conn.StreamContext(runnerContext(conn.ctx), command, handler)
+}
+
+// StreamContext runs the command without retries, because output already delivered cannot be taken back.
+func (conn *SshConnection) StreamContext(ctx context.Context, command string, handler StreamHandler) SshRunner {
+	if conn.Err != nil {
+		return conn
+	}
+	session, err := conn.Client.NewSession()
+	if err != nil {
+		conn.Err = fmt.Errorf("failed to create session for command '%s': %w", command, err)
+		return conn
+	}
+	defer session.Close()
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		conn.Err = fmt.Errorf("failed to run command '%s': %w", command, err)
+		return conn
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		conn.Err = fmt.Errorf("failed to run command '%s': %w", command, err)
+		return conn
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, release := conn.commandContext(ctx, session)
+	defer release()
+	if err := session.Start(command); err != nil {
+		conn.Err = commandError(ctx, command, err, "")
+		return conn
+	}
+	captured, handlerErr := streamLines(stdout, stderr, handler, cancel)
+	err = session.Wait()
+	switch {
+	case handlerErr != nil:
+		conn.Err = handlerErr
+	case err != nil:
+		conn.Err = commandError(ctx, command, err, captured)
+	default:
+		fmt.Fprint(conn.Stderr(), captured)
+	}
+	return conn
+}
+
+func (conn *SshConnectionMock) Stream(command string, handler StreamHandler) SshRunner {
+	return conn.StreamContext(context.Background(), command, handler)
+}
+
+func (conn *SshConnectionMock) StreamContext(ctx context.Context, command string, handler StreamHandler) SshRunner {
+	return conn.RunContext(ctx, command, func(res string) error {
+		if handler.Stdout == nil || res == "" {
+			return nil
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(strings.ReplaceAll(res, "\r\n", "\n"), "\n"), "\n") {
+			if err := handler.Stdout(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package rove
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSshConnectionStream(t *testing.T) {
+	address, _ := testSshServer(t, func(_ int32, command string, channel ssh.Channel) int {
+		switch command {
+		case "docker service logs --follow files":
+			for i := 0; ; i++ {
+				if _, err := fmt.Fprintf(channel, "line %d\n", i); err != nil {
+					return 0
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		default:
+			fmt.Fprint(channel, "one\n")
+			fmt.Fprint(channel.Stderr(), "warn\n")
+			fmt.Fprint(channel, "two")
+		}
+		return 0
+	})
+	stdout := make([]string, 0)
+	stderr := make([]string, 0)
+	errStop := errors.New("stop")
+	err := sshConnect(t.Context(), address, "deploy", ssh.Password(""), ssh.InsecureIgnoreHostKey(), nil, SshConnectOptions{}, func(conn SshRunner, _ io.Reader) error {
+		err := conn.
+			Stream("docker image pull python:3.12", StreamHandler{
+				Stderr: func(line string) error {
+					stderr = append(stderr, line)
+					return nil
+				},
+				Stdout: func(line string) error {
+					stdout = append(stdout, line)
+					return nil
+				},
+			}).
+			Error()
+		if err != nil {
+			return err
+		}
+		return conn.
+			Stream("docker service logs --follow files", StreamHandler{
+				Stdout: func(line string) error {
+					return errStop
+				},
+			}).
+			Error()
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("'%v' did not match expected.", err)
+	}
+	if !slices.Equal(stdout, []string{"one", "two"}) {
+		t.Errorf("'%#v' did not match expected.", stdout)
+	}
+	if !slices.Equal(stderr, []string{"warn"}) {
+		t.Errorf("'%#v' did not match expected.", stderr)
+	}
+}
+
+func TestLocalRunnerStream(t *testing.T) {
+	stdout := make([]string, 0)
+	stderr := make([]string, 0)
+	err := (&LocalRunner{}).
+		Stream("sh -c 'echo one; echo warn >&2; echo two'", StreamHandler{
+			Stderr: func(line string) error {
+				stderr = append(stderr, line)
+				return nil
+			},
+			Stdout: func(line string) error {
+				stdout = append(stdout, line)
+				return nil
+			},
+		}).
+		Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stdout, []string{"one", "two"}) {
+		t.Errorf("'%#v' did not match expected.", stdout)
+	}
+	if !slices.Equal(stderr, []string{"warn"}) {
+		t.Errorf("'%#v' did not match expected.", stderr)
+	}
+}
@@ -0,0 +1,334 @@
+package rove
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestSshAuthKeyFile(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "id_plain")
+	if err := os.WriteFile(plain, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, closeAuth, err := sshAuth(plain); err != nil {
+		t.Errorf("expected unencrypted key to parse: %v", err)
+	} else {
+		closeAuth()
+	}
+
+	block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte("fake-passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := filepath.Join(dir, "id_encrypted")
+	if err := os.WriteFile(encrypted, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(SshPassphraseEnv, "wrong-passphrase")
+	if _, _, err := sshAuth(encrypted); err == nil {
+		t.Error("expected encrypted key with wrong passphrase to fail.")
+	}
+	t.Setenv(SshPassphraseEnv, "fake-passphrase")
+	if _, closeAuth, err := sshAuth(encrypted); err != nil {
+		t.Errorf("expected encrypted key to parse with passphrase: %v", err)
+	} else {
+		closeAuth()
+	}
+	t.Setenv(SshPassphraseEnv, "wrong-passphrase")
+	if _, _, err := sshAuth(encrypted); err != nil {
+		t.Errorf("expected decrypted key to be reused without asking for the passphrase again: %v", err)
+	}
+}
+
+func TestSshAuthAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, _, err := sshAuth(""); err == nil {
+		t.Error("expected missing SSH agent to fail.")
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	auth, closeAuth, err := sshAuth("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAuth()
+	if auth == nil {
+		t.Error("expected agent auth method.")
+	}
+}
+
+func TestSshAuthInvalidKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "id_invalid")
+	if err := os.WriteFile(file, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sshAuth(file); !errors.Is(err, ErrorSsh{Kind: SshErrorKey}) {
+		t.Errorf("'%v' was not a key error.", err)
+	}
+}
+
+func TestSshDialErrors(t *testing.T) {
+	defaults := SshConnectDefaults
+	defer func() { SshConnectDefaults = defaults }()
+	SshConnectDefaults = SshConnectOptions{Backoff: time.Millisecond, Retries: 1, Timeout: time.Second}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+	}
+	serverConfig.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go ssh.NewServerConn(conn, serverConfig)
+		}
+	}()
+	config := &ssh.ClientConfig{
+		User:            "deploy",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	if _, err := sshDial(context.Background(), nil, listener.Addr().String(), config, SshConnectDefaults); !errors.Is(err, ErrorSsh{Kind: SshErrorAuth}) {
+		t.Errorf("'%v' was not an auth error.", err)
+	}
+	if accepted.Load() != 1 {
+		t.Errorf("expected auth failure not to be retried, got %d attempts.", accepted.Load())
+	}
+
+	config.HostKeyCallback = func(string, net.Addr, ssh.PublicKey) error {
+		return errors.New("mismatch")
+	}
+	if _, err := sshDial(context.Background(), nil, listener.Addr().String(), config, SshConnectDefaults); !errors.Is(err, ErrorSsh{Kind: SshErrorHostKey}) {
+		t.Errorf("'%v' was not a host key error.", err)
+	}
+
+	address := listener.Addr().String()
+	listener.Close()
+	if _, err := sshDial(context.Background(), nil, address, config, SshConnectDefaults); !errors.Is(err, ErrorSsh{Kind: SshErrorUnreachable}) {
+		t.Errorf("'%v' was not an unreachable error.", err)
+	}
+}
+
+// testSshServer accepts any client and passes each exec request to handler along with the number of the connection it arrived on. The handler returns the exit status, or -1 to close the connection without one, as a dropped connection would.
+func testSshServer(t *testing.T, handler func(connection int32, command string, channel ssh.Channel) int) (string, *atomic.Int32) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var connections atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connection := connections.Add(1)
+			go func() {
+				serverConn, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					return
+				}
+				defer serverConn.Close()
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						for req := range requests {
+							if req.Type != "exec" {
+								req.Reply(false, nil)
+								continue
+							}
+							req.Reply(true, nil)
+							command := string(req.Payload[4:])
+							if status := handler(connection, command, channel); status >= 0 {
+								channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+							} else {
+								serverConn.Close()
+							}
+							channel.Close()
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), &connections
+}
+
+func TestSshConnectionRun(t *testing.T) {
+	address, connections := testSshServer(t, func(connection int32, command string, channel ssh.Channel) int {
+		switch command {
+		case "sleep":
+			time.Sleep(time.Second)
+		case "docker service ls", "docker service create files":
+			if connection == 1 {
+				return -1
+			}
+		case "docker service inspect missing":
+			fmt.Fprint(channel.Stderr(), "Error: no such service: missing\n")
+			return 1
+		}
+		channel.Write([]byte(fmt.Sprintf("%s on %d", command, connection)))
+		return 0
+	})
+	options := SshConnectOptions{Backoff: time.Millisecond, CommandTimeout: 100 * time.Millisecond, Retries: 1, Timeout: time.Second}
+	err := sshConnect(context.Background(), address, "deploy", ssh.Password(""), ssh.InsecureIgnoreHostKey(), nil, options, func(conn SshRunner, _ io.Reader) error {
+		if err := conn.Run("sleep", func(string) error { return nil }).Error(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("'%v' did not time out.", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err = sshConnect(ctx, address, "deploy", ssh.Password(""), ssh.InsecureIgnoreHostKey(), nil, SshConnectOptions{}, func(conn SshRunner, _ io.Reader) error {
+		if err := conn.Run("sleep", func(string) error { return nil }).Error(); !errors.Is(err, context.Canceled) {
+			t.Errorf("'%v' was not canceled.", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connections.Store(0)
+	err = sshConnect(context.Background(), address, "deploy", ssh.Password(""), ssh.InsecureIgnoreHostKey(), nil, options, func(conn SshRunner, _ io.Reader) error {
+		return conn.Run("docker service ls", func(res string) error {
+			if res != "docker service ls on 2" {
+				t.Errorf("'%s' did not match expected.", res)
+			}
+			return nil
+		}).Error()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sshConnect(context.Background(), address, "deploy", ssh.Password(""), ssh.InsecureIgnoreHostKey(), nil, options, func(conn SshRunner, _ io.Reader) error {
+		return conn.Run("docker service inspect missing", func(string) error { return nil }).Error()
+	})
+	var errCommand CommandError
+	if !errors.As(err, &errCommand) {
+		t.Fatalf("'%v' was not a command error.", err)
+	}
+	if errCommand.ExitCode != 1 || errCommand.Stderr != "Error: no such service: missing\n" {
+		t.Errorf("'%#v' did not match expected.", errCommand)
+	}
+
+	connections.Store(0)
+	err = sshConnect(context.Background(), address, "deploy", ssh.Password(""), ssh.InsecureIgnoreHostKey(), nil, options, func(conn SshRunner, _ io.Reader) error {
+		return conn.Run("docker service create files", func(string) error { return nil }).Error()
+	})
+	if err == nil {
+		t.Error("expected command that is not read-only to fail without retrying.")
+	}
+	if connections.Load() != 1 {
+		t.Errorf("expected 1 connection, got %d.", connections.Load())
+	}
+}
+
+func TestSshReadOnlyCommand(t *testing.T) {
+	for command, expected := range map[string]bool{
+		"docker info --format json":                           true,
+		"docker network ls --format json --filter label=rove": true,
+		"docker service inspect files":                        true,
+		"docker service ls --format json":                     true,
+		"docker service create --name files nginx":            false,
+		"docker service logs files":                           false,
+		"docker servicels":                                    false,
+		"sudo ufw status":                                     false,
+	} {
+		if sshReadOnlyCommand.MatchString(command) != expected {
+			t.Errorf("'%s' expected read-only to be %t.", command, expected)
+		}
+	}
+}
+
+func TestSshConnectionMockRunContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mock := &SshConnectionMock{}
+	if err := mock.RunContext(ctx, "docker service ls", func(string) error { return nil }).Error(); !errors.Is(err, context.Canceled) {
+		t.Errorf("'%v' was not canceled.", err)
+	}
+	if len(mock.CommandsRun) != 0 {
+		t.Errorf("'%#v' should be empty.", mock.CommandsRun)
+	}
+}
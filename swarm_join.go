@@ -0,0 +1,133 @@
+package rove
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/alessio/shellescape"
+	"github.com/evantbyrne/trance"
+	"github.com/google/uuid"
+)
+
+// swarmJoin holds what a new machine needs to join the swarm of an existing manager.
+type swarmJoin struct {
+	// Address is the manager's swarm address, including the port.
+	Address string
+	// Cluster is the cluster of the manager machine, which the new machine joins.
+	Cluster string
+	// Nodes holds the IDs of nodes already in the swarm.
+	Nodes map[string]bool
+	Token string
+}
+
+func (join *swarmJoin) Command(advertiseAddress string) string {
+	return fmt.Sprintf("docker swarm join --token %s --advertise-addr %s %s", shellescape.Quote(join.Token), shellescape.Quote(advertiseAddress), shellescape.Quote(join.Address))
+}
+
+// Redact removes the join token from errors, since they are printed.
+func (join *swarmJoin) Redact(err error) error {
+	if err == nil || join.Token == "" {
+		return err
+	}
+	return swarmJoinError{Err: err, Token: join.Token}
+}
+
+// swarmJoinError hides the join token from the text of any error, including canceled and timed out commands, and from the fields of a wrapped CommandError.
+type swarmJoinError struct {
+	Err   error
+	Token string
+}
+
+func (err swarmJoinError) As(target any) bool {
+	errCommand, ok := target.(*CommandError)
+	if !ok || !errors.As(err.Err, errCommand) {
+		return false
+	}
+	errCommand.Command = err.redact(errCommand.Command)
+	errCommand.Stderr = err.redact(errCommand.Stderr)
+	return true
+}
+
+func (err swarmJoinError) Error() string {
+	return err.redact(err.Err.Error())
+}
+
+func (err swarmJoinError) Unwrap() error {
+	return err.Err
+}
+
+func (err swarmJoinError) redact(text string) string {
+	return strings.ReplaceAll(text, err.Token, "<token>")
+}
+
+// clusterNew returns a random cluster ID. Clusters are not keyed by machine name, since names can be changed and reused.
+func clusterNew() string {
+	return uuid.NewString()
+}
+
+// swarmJoinFetch connects to the manager machine with the given name and reads its join token for role.
+func swarmJoinFetch(ctx context.Context, name string, role string) (*swarmJoin, error) {
+	manager, err := trance.Query[Machine]().Filter("name", "=", name).CollectFirst()
+	if err != nil {
+		if errors.Is(err, trance.ErrorNotFound{}) {
+			return nil, fmt.Errorf("🚫 No machine with name '%s' configured", name)
+		}
+		return nil, err
+	}
+	join := &swarmJoin{
+		Cluster: manager.Cluster,
+		Nodes:   make(map[string]bool),
+	}
+	err = SshMachineContext(ctx, manager, func(conn SshRunner, _ io.Reader) error {
+		return conn.
+			Run("docker info --format json", func(res string) error {
+				var dockerInfo DockerInfoJson
+				if err := json.Unmarshal([]byte(res), &dockerInfo); err != nil {
+					fmt.Println("🚫 Could not parse docker info JSON:\n", res)
+					return err
+				}
+				if !dockerInfo.Swarm.ControlAvailable {
+					return fmt.Errorf("🚫 Machine '%s' is not a swarm manager", name)
+				}
+				join.Address = net.JoinHostPort(dockerInfo.Swarm.NodeAddr, "2377")
+				return nil
+			}).
+			Run("docker node ls --format json", func(res string) error {
+				for _, line := range strings.Split(strings.ReplaceAll(res, "\r\n", "\n"), "\n") {
+					if line != "" {
+						var dockerNodeLs DockerNodeLsJson
+						if err := json.Unmarshal([]byte(line), &dockerNodeLs); err != nil {
+							fmt.Println("🚫 Could not parse docker node ls JSON:\n", line)
+							return err
+						}
+						join.Nodes[dockerNodeLs.Id] = true
+					}
+				}
+				return nil
+			}).
+			Run(fmt.Sprint("docker swarm join-token --quiet ", shellescape.Quote(role)), func(res string) error {
+				join.Token = strings.TrimSpace(res)
+				return nil
+			}).
+			Error()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if join.Cluster == "" {
+		// Machines added before clusters were recorded form their own cluster.
+		join.Cluster = clusterNew()
+		if err := trance.Query[Machine]().
+			Filter("id", "=", manager.Id).
+			UpdateMap(map[string]any{"cluster": join.Cluster}).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+	return join, nil
+}
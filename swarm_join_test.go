@@ -0,0 +1,43 @@
+package rove
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSwarmJoinCommand(t *testing.T) {
+	join := &swarmJoin{
+		Address: "10.0.0.1:2377",
+		Token:   "SWMTKN-1-secret",
+	}
+	expected := "docker swarm join --token SWMTKN-1-secret --advertise-addr 10.0.0.2 10.0.0.1:2377"
+	if command := join.Command("10.0.0.2"); command != expected {
+		t.Errorf("'%s' did not match expected '%s'.", command, expected)
+	}
+
+	err := join.Redact(CommandError{Command: join.Command("10.0.0.2"), Err: errors.New("exit status 1")})
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("'%s' contains join token.", err)
+	}
+	var errCommand CommandError
+	if !errors.As(err, &errCommand) {
+		t.Errorf("expected CommandError, got %T.", err)
+	} else if strings.Contains(errCommand.Command, "secret") {
+		t.Errorf("'%s' contains join token.", errCommand.Command)
+	}
+	if output := errorJson(err); strings.Contains(output.Command, "secret") || strings.Contains(output.Message, "secret") {
+		t.Errorf("'%#v' contains join token.", output)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = join.Redact(commandError(ctx, join.Command("10.0.0.2"), context.Canceled, ""))
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("'%s' contains join token.", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got '%v'.", err)
+	}
+}
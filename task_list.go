@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,10 +9,12 @@ import (
 )
 
 type TaskListCommand struct {
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
-	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
-	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Json        bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
 type TaskListJson struct {
@@ -26,9 +29,10 @@ type TaskListEntryJson struct {
 	Replicas string                `json:"replicas"`
 }
 
-func (cmd *TaskListCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+func (cmd *TaskListCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, func(conn SshRunner, stdin io.Reader) error {
+			stdout := conn.Stdout()
 			output := TaskListJson{
 				Tasks: make([]TaskListEntryJson, 0),
 			}
@@ -38,7 +42,7 @@ func (cmd *TaskListCommand) Run() error {
 					if line != "" {
 						var dockerServiceLs DockerServiceLsJson
 						if err := json.Unmarshal([]byte(line), &dockerServiceLs); err != nil {
-							fmt.Println("🚫 Could not parse docker service ls JSON:\n", line)
+							fmt.Fprintln(stdout, "🚫 Could not parse docker service ls JSON:\n", line)
 							return err
 						}
 						output.Tasks = append(output.Tasks, TaskListEntryJson{
@@ -54,40 +58,39 @@ func (cmd *TaskListCommand) Run() error {
 				return err
 			}
 
-			for i, task := range output.Tasks {
-				if err := conn.Run(fmt.Sprint("docker service inspect ", task.Id), func(res string) error {
-					var dockerInspect []DockerServiceInspectJson
-					if err := json.Unmarshal([]byte(res), &dockerInspect); err != nil {
-						fmt.Println("🚫 Could not parse docker service inspect JSON:\n", res)
-						return err
-					}
-					output.Tasks[i].Command = dockerInspect[0].Spec.TaskTemplate.ContainerSpec.Args
-					for _, entry := range dockerInspect[0].Spec.EndpointSpec.Ports {
+			ids := make([]string, 0, len(output.Tasks))
+			for _, task := range output.Tasks {
+				ids = append(ids, task.Id)
+			}
+			if err := dockerServiceInspectAll(conn, ids, func(dockerInspect []DockerServiceInspectJson) error {
+				for i, inspect := range dockerInspect {
+					output.Tasks[i].Command = inspect.Spec.TaskTemplate.ContainerSpec.Args
+					for _, entry := range inspect.Spec.EndpointSpec.Ports {
 						output.Tasks[i].Ports = append(output.Tasks[i].Ports, ServiceListPortJson(entry))
 					}
-					return nil
-				}).Error(); err != nil {
-					return err
 				}
+				return nil
+			}).Error(); err != nil {
+				return err
 			}
 
 			if cmd.Json {
 				out, err := json.MarshalIndent(output, "", "    ")
 				if err != nil {
-					fmt.Println("🚫 Could not format JSON:\n", output)
+					fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", output)
 					return err
 				}
-				fmt.Println(string(out))
+				fmt.Fprintln(stdout, string(out))
 			} else {
 				for _, task := range output.Tasks {
 					ports := []string{}
 					for _, entry := range task.Ports {
 						ports = append(ports, fmt.Sprintf("%d:%d/%s", entry.TargetPort, entry.PublishedPort, entry.Protocol))
 					}
-					fmt.Println(task.Id, task.Image, task.Command, task.Replicas, strings.Join(ports, ","))
+					fmt.Fprintln(stdout, task.Id, task.Image, task.Command, task.Replicas, strings.Join(ports, ","))
 				}
 			}
 			return nil
 		})
-	})
+	}))
 }
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"fmt"
 	"io"
 )
@@ -18,17 +19,13 @@ type VolumeAddCommand struct {
 	Scope         string   `flag:"" name:"scope" help:"Cluster Volume access scope (single, multi)."`
 	Type          string   `flag:"" name:"type" help:"Cluster Volume access type (mount, block)."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Force      bool   `flag:"" name:"force" help:"Skip confirmations."`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
-func (cmd *VolumeAddCommand) Do(conn SshRunner, stdin io.Reader) error {
-	fmt.Printf("\nRove will create the '%s' volume.\n", cmd.Name)
-	if err := confirmDeployment(cmd.Force, stdin); err != nil {
-		return err
-	}
+func (cmd *VolumeAddCommand) Command() ShellCommand {
 	command := ShellCommand{
 		Name: "docker volume create",
 		Flags: []ShellFlag{
@@ -86,8 +83,16 @@ func (cmd *VolumeAddCommand) Do(conn SshRunner, stdin io.Reader) error {
 			Value: opt,
 		})
 	}
+	return command
+}
+
+func (cmd *VolumeAddCommand) Do(conn SshRunner, stdin io.Reader) error {
+	fmt.Printf("\nRove will create the '%s' volume.\n", cmd.Name)
+	if err := confirmDeployment(cmd.Force, stdin); err != nil {
+		return err
+	}
 	return conn.
-		Run(command.String(), func(res string) error {
+		Run(cmd.Command().String(), func(res string) error {
 			fmt.Printf("\nCreated '%s' volume.\n\n", cmd.Name)
 			return nil
 		}).
@@ -100,8 +105,8 @@ func (cmd *VolumeAddCommand) Do(conn SshRunner, stdin io.Reader) error {
 		Error()
 }
 
-func (cmd *VolumeAddCommand) Run() error {
+func (cmd *VolumeAddCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, cmd.Do)
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, cmd.Do)
 	})
 }
@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,14 +12,14 @@ import (
 type VolumeInspectCommand struct {
 	Name string `arg:"" name:"name" help:"Name of volume."`
 
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
+	ConfigFile string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
 	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
 	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
-func (cmd *VolumeInspectCommand) Run() error {
+func (cmd *VolumeInspectCommand) Run(ctx context.Context) error {
 	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+		return SshMachineByNameContext(ctx, cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
 			return conn.
 				Run(fmt.Sprint("docker volume inspect ", shellescape.Quote(cmd.Name)), func(res string) error {
 					var dockerVolumeInspect []map[string]any
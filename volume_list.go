@@ -1,6 +1,7 @@
 package rove
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,15 +27,18 @@ type VolumeJson struct {
 }
 
 type VolumeListCommand struct {
-	ConfigFile string `flag:"" name:"config" help:"Config file." type:"path" default:".rove"`
-	Json       bool   `flag:"" name:"json" help:"Output as JSON."`
-	Local      bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
-	Machine    string `flag:"" name:"machine" help:"Name of machine." default:""`
+	AllMachines bool   `flag:"" name:"all-machines" help:"Run on every configured machine in parallel."`
+	ConfigFile  string `flag:"" name:"config" help:"Config file. Defaults to the nearest .rove file, then $XDG_CONFIG_HOME/rove/config." type:"path" env:"ROVE_CONFIG"`
+	Group       string `flag:"" name:"group" help:"Run on every machine in group in parallel."`
+	Json        bool   `flag:"" name:"json" help:"Output as JSON."`
+	Local       bool   `flag:"" name:"local" help:"Skip SSH and run on local machine."`
+	Machine     string `flag:"" name:"machine" help:"Name of machine." default:""`
 }
 
-func (cmd *VolumeListCommand) Run() error {
-	return Database(cmd.ConfigFile, func() error {
-		return SshMachineByName(cmd.Local, cmd.Machine, func(conn SshRunner, stdin io.Reader) error {
+func (cmd *VolumeListCommand) Run(ctx context.Context) error {
+	return jsonError(cmd.Json, Database(cmd.ConfigFile, func() error {
+		return SshMachinesContext(ctx, cmd.Local, cmd.Machine, cmd.Group, cmd.AllMachines, cmd.Json, func(conn SshRunner, stdin io.Reader) error {
+			stdout := conn.Stdout()
 			return conn.
 				Run("docker volume ls --format json --filter label=rove", func(res string) error {
 					output := make([]DockerVolumeLsJson, 0)
@@ -42,7 +46,7 @@ func (cmd *VolumeListCommand) Run() error {
 						if line != "" {
 							var dockerVolumeLs DockerVolumeLsJson
 							if err := json.Unmarshal([]byte(line), &dockerVolumeLs); err != nil {
-								fmt.Println("🚫 Could not parse docker volume ls JSON:\n", line)
+								fmt.Fprintln(stdout, "🚫 Could not parse docker volume ls JSON:\n", line)
 								return err
 							}
 							output = append(output, dockerVolumeLs)
@@ -54,18 +58,18 @@ func (cmd *VolumeListCommand) Run() error {
 						}
 						out, err := json.MarshalIndent(t, "", "    ")
 						if err != nil {
-							fmt.Println("🚫 Could not format JSON:\n", t)
+							fmt.Fprintln(stdout, "🚫 Could not format JSON:\n", t)
 							return err
 						}
-						fmt.Println(string(out))
+						fmt.Fprintln(stdout, string(out))
 					} else {
 						for _, dockerVolumeLs := range output {
-							fmt.Println(dockerVolumeLs.Name, dockerVolumeLs.Availability)
+							fmt.Fprintln(stdout, dockerVolumeLs.Name, dockerVolumeLs.Availability)
 						}
 					}
 					return nil
 				}).
 				Error()
 		})
-	})
+	}))
 }